@@ -0,0 +1,86 @@
+package ida
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// FragmentEncrypted and ReconstructEncrypted apply Rivest's all-or-nothing transform (AONT) to the data
+// around dispersal, so that fragments too few to reconstruct the whole reveal nothing about the data,
+// as raw fragments do: any m of them give the data itself, and fewer give linear combinations of it.
+// The data is encrypted by AES-256 in counter mode with a random key, used only once,
+// and the package dispersed is the ciphertext followed by the key xored with the SHA-256 hash of the ciphertext.
+// The key can be recovered only from the whole package, so there is no key to store separately.
+
+const aontKey = 32 // length of the AES-256 key, and of the SHA-256 hash that masks it
+
+var ErrNotEncrypted = errors.New("reconstructed data is not an encrypted package")
+
+// FragmentEncrypted returns n fragments of the all-or-nothing package of data, any m of which are enough
+// for [ReconstructEncrypted] to recover the data, as for [Encode].
+// It also returns the SHA-256 hash of the package, the fragments' Digest,
+// which identifies the object without revealing anything about the data, as a hash of the data would.
+// It returns an error if m < 1 or n < m, or if the entropy source fails.
+func FragmentEncrypted(data []byte, m, n int) ([]*Frag, []byte, error) {
+	if m < 1 {
+		return nil, nil, ErrInvalidM
+	}
+	if n < m {
+		return nil, nil, ErrInvalidN
+	}
+	key := make([]byte, aontKey)
+	defer clear(key)
+	if _, err := crand.Read(key); err != nil {
+		return nil, nil, fmt.Errorf("cannot generate key: %w", err)
+	}
+	pkg := make([]byte, len(data)+aontKey)
+	defer clear(pkg)
+	aontStream(key).XORKeyStream(pkg[0:len(data)], data)
+	h := sha256.Sum256(pkg[0:len(data)])
+	for i, k := range key {
+		pkg[len(data)+i] = k ^ h[i]
+	}
+	frags, err := Encode(pkg, m, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	return frags, digest(pkg), nil
+}
+
+// ReconstructEncrypted returns the data from which frags were made by [FragmentEncrypted],
+// reconstructing the package as [ReconstructSecure] does, checking it against the Digest,
+// and inverting the transform.
+// It returns ErrNotEncrypted if the package is too short to hold a key.
+func ReconstructEncrypted(frags []*Frag) ([]byte, error) {
+	pkg, err := ReconstructSecure(frags)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(pkg)
+	if len(pkg) < aontKey {
+		return nil, ErrNotEncrypted
+	}
+	c := pkg[0 : len(pkg)-aontKey]
+	key := make([]byte, aontKey)
+	defer clear(key)
+	h := sha256.Sum256(c)
+	for i := range key {
+		key[i] = pkg[len(c)+i] ^ h[i]
+	}
+	data := make([]byte, len(c))
+	aontStream(key).XORKeyStream(data, c)
+	return data, nil
+}
+
+// aontStream returns the AES-256-CTR key stream for key. The key is used once, so the IV is zero.
+func aontStream(key []byte) cipher.Stream {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err) // the key is always 32 bytes
+	}
+	return cipher.NewCTR(b, make([]byte, aes.BlockSize))
+}
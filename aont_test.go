@@ -0,0 +1,57 @@
+package ida
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFragmentEncrypted(t *testing.T) {
+	data := []byte("attack at dawn, attack at dawn, attack at dawn")
+	for _, n := range []int{0, 1, len(data)} {
+		frags, id, err := FragmentEncrypted(data[0:n], 3, 5)
+		if err != nil {
+			t.Fatalf("FragmentEncrypted(%d): %v", n, err)
+		}
+		if !bytes.Equal(id, frags[0].Digest) {
+			t.Errorf("FragmentEncrypted(%d): returned %x, Digest %x", n, id, frags[0].Digest)
+		}
+		zot, err := ReconstructEncrypted(frags[2:])
+		if err != nil || !bytes.Equal(zot, data[0:n]) {
+			t.Errorf("ReconstructEncrypted(%d): want %q got %q, %v", n, data[0:n], zot, err)
+		}
+	}
+
+	frags, _, err := FragmentEncrypted(data, 3, 5)
+	if err != nil {
+		t.Fatalf("FragmentEncrypted: %v", err)
+	}
+	// the package itself is not the data, nor does it contain it
+	pkg, err := Reconstruct(frags)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if len(pkg) != len(data)+aontKey || bytes.Contains(pkg, []byte("attack")) {
+		t.Errorf("package of %d bytes reveals the data: %q", len(pkg), pkg)
+	}
+	// and differs each time, with a fresh key
+	again, _, err := FragmentEncrypted(data, 3, 5)
+	if err != nil {
+		t.Fatalf("FragmentEncrypted: %v", err)
+	}
+	if bytes.Equal(again[0].Digest, frags[0].Digest) {
+		t.Errorf("FragmentEncrypted: same package twice")
+	}
+	if _, err := ReconstructEncrypted(frags[0:2]); err != ErrTooFewFragments {
+		t.Errorf("ReconstructEncrypted of 2: want %v got %v", ErrTooFewFragments, err)
+	}
+	plain, err := Encode([]byte("short"), 2, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := ReconstructEncrypted(plain); err != ErrNotEncrypted {
+		t.Errorf("ReconstructEncrypted of plain fragments: want %v got %v", ErrNotEncrypted, err)
+	}
+	if _, _, err := FragmentEncrypted(data, 3, 2); err != ErrInvalidN {
+		t.Errorf("FragmentEncrypted(3, 2): want %v got %v", ErrInvalidN, err)
+	}
+}
@@ -0,0 +1,53 @@
+package ida
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+const armorType = "IDA FRAGMENT"
+
+var ErrBadArmor = errors.New("invalid armored fragment")
+
+// Armor returns f as text, for sharing by mail, chat, or on paper:
+// the binary encoding of f in base64, in PEM form, with a Checksum header
+// giving the CRC-32 of the binary encoding, which [ParseArmor] checks.
+// It returns the empty string if f has no binary encoding (see [Frag.MarshalBinary]),
+// which ParseArmor rejects.
+func (f *Frag) Armor() string {
+	buf, err := f.MarshalBinary()
+	if err != nil {
+		return ""
+	}
+	b := &pem.Block{
+		Type:    armorType,
+		Headers: map[string]string{"Checksum": fmt.Sprintf("%08x", crc32.ChecksumIEEE(buf))},
+		Bytes:   buf,
+	}
+	return string(pem.EncodeToMemory(b))
+}
+
+// ParseArmor returns the fragment in s, as produced by Armor, ignoring any surrounding white space.
+// It returns an error if s does not hold an armored fragment, or its checksum does not match.
+func ParseArmor(s string) (*Frag, error) {
+	b, rest := pem.Decode([]byte(strings.TrimSpace(s)))
+	if b == nil || b.Type != armorType || len(strings.TrimSpace(string(rest))) != 0 {
+		return nil, fmt.Errorf("%w: not a fragment", ErrBadArmor)
+	}
+	sum, err := strconv.ParseUint(b.Headers["Checksum"], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing or invalid checksum", ErrBadArmor)
+	}
+	if uint32(sum) != crc32.ChecksumIEEE(b.Bytes) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrBadArmor)
+	}
+	f := new(Frag)
+	if err := f.UnmarshalBinary(b.Bytes); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,44 @@
+package ida
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArmor(t *testing.T) {
+	frags, err := Encode([]byte("a paper backup"), 2, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	s := frags[1].Armor()
+	if !strings.HasPrefix(s, "-----BEGIN IDA FRAGMENT-----\n") {
+		t.Errorf("Armor: bad header:\n%s", s)
+	}
+	f, err := ParseArmor("\n\t  " + s + "\n\n")
+	if err != nil {
+		t.Fatalf("ParseArmor: %v", err)
+	}
+	if !reflect.DeepEqual(f, frags[1]) {
+		t.Errorf("ParseArmor: want %#v got %#v", frags[1], f)
+	}
+	lines := strings.Split(s, "\n")
+	body := lines[3] // after the BEGIN line, the header and a blank line
+	c := byte('A')
+	if body[5] == 'A' {
+		c = 'B'
+	}
+	lines[3] = body[0:5] + string(c) + body[6:]
+	if _, err := ParseArmor(strings.Join(lines, "\n")); !errors.Is(err, ErrBadArmor) {
+		t.Errorf("ParseArmor of altered text: want %v got %v", ErrBadArmor, err)
+	}
+	for _, bad := range []string{"", "hello", strings.Replace(s, "IDA FRAGMENT", "CERTIFICATE", 2), strings.Replace(s, "Checksum", "Chksum", 1), s + s} {
+		if _, err := ParseArmor(bad); !errors.Is(err, ErrBadArmor) {
+			t.Errorf("ParseArmor(%q): want %v got %v", bad, ErrBadArmor, err)
+		}
+	}
+	if s := (&Frag{M: -1}).Armor(); s != "" {
+		t.Errorf("Armor of invalid fragment: want empty string got %q", s)
+	}
+}
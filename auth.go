@@ -0,0 +1,69 @@
+package ida
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// FragmentAuth is like [Encode] but also sets the MAC of each fragment to an HMAC-SHA256 under key,
+// so that a fragment substituted by an adversary, whose CRC and values would pass, can still be detected,
+// by [Frag.VerifyAuth], [ConsistentAuth] or [ReconstructAuth] with the same key.
+func FragmentAuth(data []byte, m, n int, key []byte) ([]*Frag, error) {
+	frags, err := Encode(data, m, n)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range frags {
+		if f.MAC, err = f.mac(key); err != nil {
+			return nil, err
+		}
+	}
+	return frags, nil
+}
+
+// VerifyAuth returns true if f has a MAC, and it is the HMAC of the rest of f under key.
+// A fragment that cannot be encoded has no valid MAC.
+func (f *Frag) VerifyAuth(key []byte) bool {
+	if len(f.MAC) != sha256.Size {
+		return false
+	}
+	mac, err := f.mac(key)
+	return err == nil && hmac.Equal(f.MAC, mac)
+}
+
+// mac returns the HMAC-SHA256 under key of the binary encoding of f without its MAC,
+// which covers all the other fields, or an error if f cannot be encoded.
+func (f *Frag) mac(key []byte) ([]byte, error) {
+	g := *f
+	g.MAC = nil
+	buf, err := g.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(buf)
+	return h.Sum(nil), nil
+}
+
+// ConsistentAuth is like [Consistent] but first discards the fragments that fail VerifyAuth with key,
+// so that they have no vote.
+func ConsistentAuth(frags []*Frag, key []byte) ([]*Frag, error) {
+	return Consistent(authentic(frags, key))
+}
+
+// ReconstructAuth is like [Reconstruct] but uses only the fragments that pass VerifyAuth with key.
+// Errors about particular fragments give their indices in frags.
+func ReconstructAuth(frags []*Frag, key []byte) ([]byte, error) {
+	return Reconstruct(authentic(frags, key))
+}
+
+// authentic returns a copy of frags with nil in place of each fragment that fails VerifyAuth with key.
+func authentic(frags []*Frag, key []byte) []*Frag {
+	out := make([]*Frag, len(frags))
+	for i, f := range frags {
+		if f != nil && f.VerifyAuth(key) {
+			out[i] = f
+		}
+	}
+	return out
+}
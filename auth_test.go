@@ -0,0 +1,103 @@
+package ida
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFragmentAuth(t *testing.T) {
+	data := []byte("only those who hold the key may contribute")
+	key := []byte("shared secret")
+	frags, err := FragmentAuth(data, 3, 5, key)
+	if err != nil {
+		t.Fatalf("FragmentAuth: %v", err)
+	}
+	for i, f := range frags {
+		if !f.VerifyAuth(key) {
+			t.Errorf("VerifyAuth: fragment %d fails with the right key", i)
+		}
+		if f.VerifyAuth([]byte("wrong")) {
+			t.Errorf("VerifyAuth: fragment %d passes with the wrong key", i)
+		}
+	}
+
+	// a forgery made from the same data, with a good CRC, but no key
+	other, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	forged := other[0].Clone()
+	forged.SetID = frags[0].SetID
+	forged.Enc[0] = (forged.Enc[0] + 1) % Prime
+	forged.CRC = forged.checksum()
+	forged.MAC = frags[0].MAC
+	if forged.VerifyAuth(key) {
+		t.Errorf("VerifyAuth: forgery passes")
+	}
+	tampered := frags[1].Clone()
+	tampered.Index = 7
+	if tampered.VerifyAuth(key) {
+		t.Errorf("VerifyAuth: tampered Index passes")
+	}
+	if (&Frag{M: 1, A: []Field{1}}).VerifyAuth(key) {
+		t.Errorf("VerifyAuth: fragment without MAC passes")
+	}
+	// a fragment that cannot be encoded has no HMAC to compare, so an empty MAC must not match it
+	for _, c := range []struct {
+		name   string
+		modify func(*Frag)
+	}{
+		{"Index", func(f *Frag) { f.Index = -5 }},
+		{"Len", func(f *Frag) { f.Len = -1 }},
+	} {
+		unenc := frags[0].Clone()
+		unenc.Enc[0] = (unenc.Enc[0] + 1) % Prime
+		c.modify(unenc)
+		unenc.MAC = []byte{}
+		if unenc.VerifyAuth(key) {
+			t.Errorf("VerifyAuth: unencodable fragment (%s) with empty MAC passes", c.name)
+		}
+		if _, err := ReconstructAuth([]*Frag{unenc, frags[1], frags[2]}, key); err != ErrTooFewFragments {
+			t.Errorf("ReconstructAuth with unencodable fragment (%s): want %v got %v", c.name, ErrTooFewFragments, err)
+		}
+	}
+
+	set := []*Frag{forged, frags[1], forged, frags[3], frags[4]}
+	if _, err := Reconstruct(set); err == nil {
+		t.Errorf("Reconstruct with forgeries: want error")
+	}
+	zot, err := ReconstructAuth(set, key)
+	if err != nil || !bytes.Equal(zot, data) {
+		t.Errorf("ReconstructAuth: want %q got %q, %v", data, zot, err)
+	}
+	good, err := ConsistentAuth(set, key)
+	if err != nil || len(good) != 3 {
+		t.Errorf("ConsistentAuth: want 3 fragments got %d, %v", len(good), err)
+	}
+	if _, err := ReconstructAuth(frags, []byte("wrong")); err != ErrTooFewFragments {
+		t.Errorf("ReconstructAuth with the wrong key: want %v got %v", ErrTooFewFragments, err)
+	}
+
+	// the MAC survives encoding
+	for i, f := range frags[0:2] {
+		buf, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: %v", err)
+		}
+		var g Frag
+		if err := g.UnmarshalBinary(buf); err != nil || !g.VerifyAuth(key) {
+			t.Errorf("binary round trip of fragment %d: MAC lost, %v", i, err)
+		}
+		if len(buf) != f.Size() {
+			t.Errorf("Size: want %d got %d", len(buf), f.Size())
+		}
+		js, err := f.MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON: %v", err)
+		}
+		var h Frag
+		if err := h.UnmarshalJSON(js); err != nil || !h.VerifyAuth(key) {
+			t.Errorf("JSON round trip of fragment %d: MAC lost, %v", i, err)
+		}
+	}
+}
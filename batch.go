@@ -0,0 +1,118 @@
+package ida
+
+import (
+	"bytes"
+	"slices"
+)
+
+// EncodeBatch returns n fragments of each of objs, with the same n encoding rows used for every object,
+// as for [Encoder.EncodeBatch] with the default source of random numbers.
+func EncodeBatch(objs [][]byte, m, n int) ([][]*Frag, error) {
+	return NewEncoder(m, nil).EncodeBatch(objs, n)
+}
+
+// EncodeBatch returns n fragments of each of objs, any m of which are normally enough to reconstruct it,
+// where out[i][j] is fragment j of objs[i], with Index j+1.
+// The n encoding rows are drawn once, and fragment j of every object has row j,
+// so that a [BatchDecoder] can invert the matrix of m of them once, and decode every object with it,
+// which for many small objects saves most of the cost of reconstruction, and all but one copy of the rows
+// if the caller stores them once.
+//
+// Sharing the rows correlates the objects. The rows travel in every fragment, so
+// anyone holding m fragments of one object has the inverse that decodes the fragments
+// with the same indices of every other object in the batch, without further work;
+// and in Z(Prime) the difference between fragment j of two objects is fragment j of the
+// difference of the objects. (IDA gives no secrecy in any case: any fragment reveals
+// something of its object.) Objects that must be kept apart should be in separate batches.
+func (e *Encoder) EncodeBatch(objs [][]byte, n int) ([][]*Frag, error) {
+	if err := e.check(n); err != nil {
+		return nil, err
+	}
+	rows := e.rows(n)
+	out := make([][]*Frag, len(objs))
+	for i, data := range objs {
+		dg := digest(data)
+		frags := make([]*Frag, n)
+		id := e.setID()
+		for j, a := range rows {
+			frags[j] = newFrag(int64(len(data)), slices.Clone(a), e.encode(data, a), slices.Clone(dg))
+			frags[j].member(j+1, id)
+		}
+		out[i] = frags
+	}
+	return out, nil
+}
+
+// BatchDecoder reconstructs objects encoded by EncodeBatch from fragments with
+// the same m encoding rows, inverting their matrix once, when the BatchDecoder is made.
+// It works only in Z(Prime).
+// A BatchDecoder is safe for concurrent use.
+type BatchDecoder struct {
+	dec *Decoder
+}
+
+// NewBatchDecoder returns a BatchDecoder using the encoding rows of m of frags,
+// the fragments of one object of a batch that will be available for all of them,
+// chosen as by [SelectIndependent].
+func NewBatchDecoder(frags []*Frag) (*BatchDecoder, error) {
+	sel, err := SelectIndependent(frags)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]Field, len(sel))
+	for i, f := range sel {
+		rows[i] = f.A
+	}
+	dec, err := NewDecoder(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchDecoder{dec: dec}, nil
+}
+
+// M returns the number of encoding rows, and so of fragments needed for reconstruction.
+func (bd *BatchDecoder) M() int {
+	return bd.dec.M()
+}
+
+// Reconstruct returns the data of one object of the batch, given its fragments,
+// which must include one with each of bd's encoding rows, in any order; others are ignored.
+// The result is checked against the fragments' Digest, if they have one.
+// It returns ErrTooFewFragments if a row is missing, and a [*FragmentError] if a fragment
+// used is inconsistent with the others.
+func (bd *BatchDecoder) Reconstruct(frags []*Frag) ([]byte, error) {
+	a := bd.dec.a
+	m := len(a)
+	d := &decoder{m: m, frags: make([]*Frag, m), used: make([]int, m), a: a, ainv: bd.dec.ainv, sys: bd.dec.ainv == nil}
+	for i, row := range a {
+		j := slices.IndexFunc(frags, func(f *Frag) bool { return f != nil && slices.Equal(f.A, row) })
+		if j < 0 {
+			return nil, ErrTooFewFragments
+		}
+		d.frags[i] = frags[j]
+		d.used[i] = j
+	}
+	f0 := d.frags[0]
+	d.fraglen = len(f0.Enc)
+	for i, f := range d.frags {
+		if err := f.shape(); err != nil {
+			return nil, &FragmentError{d.used[i], err}
+		}
+		if f.Len != f0.Len || !bytes.Equal(f.Digest, f0.Digest) {
+			return nil, &FragmentError{d.used[i], ErrInconsistentFragment}
+		}
+	}
+	d.digest = f0.Digest
+	dlen, err := d.size()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, dlen)
+	if err := d.decode(out); err != nil {
+		return nil, err
+	}
+	if err := d.check(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
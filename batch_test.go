@@ -0,0 +1,109 @@
+package ida
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestEncodeBatch(t *testing.T) {
+	objs := make([][]byte, 50)
+	for i := range objs {
+		objs[i] = []byte(fmt.Sprintf("object %d of the batch", i))
+	}
+	objs[7] = nil
+	batch, err := NewEncoder(3, rand.NewSource(69)).EncodeBatch(objs, 5)
+	if err != nil {
+		t.Fatalf("EncodeBatch: %v", err)
+	}
+	for i, frags := range batch {
+		if len(frags) != 5 {
+			t.Fatalf("object %d: %d fragments", i, len(frags))
+		}
+		for j, f := range frags {
+			if !slices.Equal(f.A, batch[0][j].A) || f.Index != j+1 {
+				t.Fatalf("object %d fragment %d: row or index differs from the batch", i, j)
+			}
+		}
+	}
+	avail := []int{4, 1, 2} // the fragments surviving for every object
+	pick := func(frags []*Frag) []*Frag {
+		var out []*Frag
+		for _, j := range avail {
+			out = append(out, frags[j])
+		}
+		return out
+	}
+	bd, err := NewBatchDecoder(pick(batch[0]))
+	if err != nil {
+		t.Fatalf("NewBatchDecoder: %v", err)
+	}
+	if bd.M() != 3 {
+		t.Errorf("M: want 3 got %d", bd.M())
+	}
+	for i, frags := range batch {
+		out, err := bd.Reconstruct(pick(frags))
+		if err != nil || !bytes.Equal(out, objs[i]) {
+			t.Errorf("object %d: want %q got %q (%v)", i, objs[i], out, err)
+		}
+		// Reconstruct agrees
+		out, err = Reconstruct(frags[1:4])
+		if err != nil || !bytes.Equal(out, objs[i]) {
+			t.Errorf("object %d: Reconstruct: want %q got %q (%v)", i, objs[i], out, err)
+		}
+	}
+	if _, err := bd.Reconstruct(batch[3][0:2]); err != ErrTooFewFragments {
+		t.Errorf("missing row: want %v got %v", ErrTooFewFragments, err)
+	}
+	mixed := []*Frag{batch[1][4], batch[2][1], batch[1][2]}
+	var fe *FragmentError
+	if _, err := bd.Reconstruct(mixed); !errors.As(err, &fe) || fe.Index != 1 {
+		t.Errorf("fragments of different objects: want FragmentError for 1, got %v", err)
+	}
+	if _, err := EncodeBatch(objs, 3, 2); err != ErrInvalidN {
+		t.Errorf("n < m: want %v got %v", ErrInvalidN, err)
+	}
+}
+
+func BenchmarkBatchDecoder(b *testing.B) {
+	objs := make([][]byte, 1000)
+	for i := range objs {
+		objs[i] = make([]byte, 64)
+		rand.Read(objs[i])
+	}
+	batch, err := EncodeBatch(objs, 8, 12)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bd, err := NewBatchDecoder(batch[0][4:])
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, frags := range batch {
+			bd.Reconstruct(frags[4:])
+		}
+	}
+}
+
+func BenchmarkBatchReconstruct(b *testing.B) {
+	objs := make([][]byte, 1000)
+	for i := range objs {
+		objs[i] = make([]byte, 64)
+		rand.Read(objs[i])
+	}
+	batch, err := EncodeBatch(objs, 8, 12)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, frags := range batch {
+			Reconstruct(frags[4:])
+		}
+	}
+}
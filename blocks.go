@@ -0,0 +1,105 @@
+package ida
+
+import (
+	"crypto/sha256"
+	"hash"
+	"slices"
+)
+
+// appender holds the state of a set of fragments being built by Encoder.Append.
+type appender struct {
+	frags []*Frag
+	pend  []byte // data left over after the last whole column, fewer than 2m bytes
+	h     hash.Hash
+}
+
+// Append adds a block of data to the set of e.N fragments being built, which the first call starts,
+// and [Encoder.Finish] ends.
+// The blocks are encoded as if concatenated, so each fragment has one encoding row,
+// and an Enc that grows with each block, while its Blocks records the blocks' lengths.
+// Append returns an error if e.M < 1 or e.N < e.M.
+func (e *Encoder) Append(data []byte) error {
+	if e.app == nil {
+		if err := e.check(e.N); err != nil {
+			return err
+		}
+		e.app = &appender{frags: make([]*Frag, e.N), h: sha256.New()}
+		rows, id := e.rows(e.N), e.setID()
+		for i, a := range rows {
+			e.app.frags[i] = &Frag{M: e.M, A: a, Enc: []int{}, Index: i + 1, SetID: id, Blocks: []int64{}}
+		}
+	}
+	app := e.app
+	app.h.Write(data)
+	app.pend = append(app.pend, data...)
+	// encode only whole columns, so a short block does not leave padding in the middle of the data
+	col := wordBytes(e.order()) * e.M
+	whole := len(app.pend) / col * col
+	for _, f := range app.frags {
+		f.Len += int64(len(data))
+		f.Blocks = append(f.Blocks, int64(len(data)))
+		if whole > 0 {
+			f.Enc = append(f.Enc, e.encode(app.pend[0:whole], f.A)...)
+		}
+	}
+	app.pend = append(app.pend[0:0], app.pend[whole:]...)
+	return nil
+}
+
+// Finish encodes what remains of the blocks given to Append, and returns the completed fragments,
+// which [Reconstruct] turns back into the concatenation of the blocks, and [ReconstructBlocks] into the blocks.
+// The Encoder is then ready to start a new set.
+// Finish returns nil if Append has not been called.
+func (e *Encoder) Finish() []*Frag {
+	app := e.app
+	if app == nil {
+		return nil
+	}
+	e.app = nil
+	dg := app.h.Sum(nil)
+	for _, f := range app.frags {
+		if len(app.pend) > 0 {
+			f.Enc = append(f.Enc, e.encode(app.pend, f.A)...)
+		}
+		f.Digest = slices.Clone(dg)
+		f.CRC = f.checksum()
+	}
+	return app.frags
+}
+
+// ReconstructBlocks is like [Reconstruct] but returns the data as the blocks given to Encoder.Append,
+// or as a single block if the fragments have no Blocks.
+func ReconstructBlocks(frags []*Frag) ([][]byte, error) {
+	d, err := selectDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	blocks := d.frags[0].Blocks
+	if blocks != nil && badblocks(blocks, d.frags[0].Len) {
+		return nil, ErrInconsistentFragment
+	}
+	data, err := d.reconstruct()
+	if err != nil {
+		return nil, err
+	}
+	if blocks == nil {
+		return [][]byte{data}, nil
+	}
+	out := make([][]byte, len(blocks))
+	for i, n := range blocks {
+		out[i] = data[0:n:n]
+		data = data[n:]
+	}
+	return out, nil
+}
+
+// badblocks returns true if the block lengths are not all positive or zero, summing to dlen.
+func badblocks(blocks []int64, dlen int64) bool {
+	for _, n := range blocks {
+		if n < 0 || n > dlen {
+			return true
+		}
+		dlen -= n
+	}
+	return dlen != 0
+}
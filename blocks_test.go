@@ -0,0 +1,94 @@
+package ida
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	e := NewEncoder(3, rand.NewSource(1))
+	e.N = 5
+	if e.Finish() != nil {
+		t.Errorf("Finish without Append: want nil")
+	}
+	var blocks [][]byte
+	var all []byte
+	for _, n := range []int{5, 0, 13, 1, 64, 3} {
+		b := make([]byte, n)
+		rand.Read(b)
+		blocks = append(blocks, b)
+		all = append(all, b...)
+		if err := e.Append(b); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	frags := e.Finish()
+	if len(frags) != 5 {
+		t.Fatalf("Finish: want 5 fragments got %d", len(frags))
+	}
+	for i, f := range frags {
+		if !slices.Equal(f.Enc, encode(all, f.A)) || f.Len != int64(len(all)) || !f.Verify() {
+			t.Errorf("fragment %d: not the encoding of the concatenated blocks", i)
+		}
+	}
+	zot, err := Reconstruct(frags[2:])
+	if err != nil || !bytes.Equal(zot, all) {
+		t.Errorf("Reconstruct: data differs, %v", err)
+	}
+	got, err := ReconstructBlocks(frags[1:4])
+	if err != nil {
+		t.Fatalf("ReconstructBlocks: %v", err)
+	}
+	if len(got) != len(blocks) {
+		t.Fatalf("ReconstructBlocks: want %d blocks got %d", len(blocks), len(got))
+	}
+	for i := range got {
+		if !bytes.Equal(got[i], blocks[i]) {
+			t.Errorf("block %d differs", i)
+		}
+	}
+	for _, f := range frags[0:2] {
+		buf, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: %v", err)
+		}
+		var g Frag
+		if err := g.UnmarshalBinary(buf); err != nil || !reflect.DeepEqual(f, &g) {
+			t.Errorf("binary encoding: Blocks lost: %v", err)
+		}
+		buf, err = json.Marshal(f)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		var h Frag
+		if err := json.Unmarshal(buf, &h); err != nil || !reflect.DeepEqual(f, &h) {
+			t.Errorf("JSON encoding: Blocks lost: %v", err)
+		}
+	}
+	frags[0].Blocks[0]++
+	if frags[0].Verify() {
+		t.Errorf("CRC does not cover Blocks")
+	}
+	buf, _ := frags[0].MarshalBinary()
+	var g Frag
+	if err := g.UnmarshalBinary(buf); err == nil {
+		t.Errorf("UnmarshalBinary: Blocks not summing to Len accepted")
+	}
+
+	// a new set can be started
+	if err := e.Append([]byte("again")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	frags = e.Finish()
+	if zot, err := Reconstruct(frags); err != nil || string(zot) != "again" {
+		t.Errorf("second set: want %q got %q, %v", "again", zot, err)
+	}
+	e.N = 2
+	if err := e.Append(nil); err != ErrInvalidN {
+		t.Errorf("Append with N < M: want %v got %v", ErrInvalidN, err)
+	}
+}
@@ -13,6 +13,8 @@ import (
 
 var prefix = `// Coded generated by mkidatab; DO NOT EDIT
 
+//go:build !idanotab && !crypto
+
 package ida
 
 var invtab = []Field{
@@ -0,0 +1,61 @@
+package ida
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Verify returns true if f's CRC matches its contents.
+// It returns false if the CRC does not match, or if f has none (its CRC is zero).
+func (f *Frag) Verify() bool {
+	return f.CRC != 0 && f.CRC == f.checksum()
+}
+
+// badcrc returns true if f has a CRC that does not match its contents.
+func badcrc(f *Frag) bool {
+	return f.CRC != 0 && f.CRC != f.checksum()
+}
+
+// member makes f, which must have no SetID and a correct CRC, fragment i of set id, with Index i,
+// updating the CRC without recomputing it, since the SetID comes last.
+func (f *Frag) member(i int, id uint64) {
+	f.Index = i
+	f.SetID = id
+	f.CRC = crc32.Update(f.CRC, crc32.IEEETable, binary.LittleEndian.AppendUint64(nil, id))
+}
+
+// checksum returns the CRC-32 (IEEE) of the little-endian representation of f's Len, M, A, Enc and Blocks,
+// in that order, with Len, M and the elements of Blocks as 64-bit values and the elements of A and Enc as 32-bit values,
+// followed by Pad and then SetID as 64-bit values if they are not zero, so that the CRC of a fragment without them is unchanged.
+// SetID must come last, for member.
+func (f *Frag) checksum() uint32 {
+	buf := make([]byte, 0, 4096)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(f.Len))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(f.M))
+	for _, v := range f.A {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+	}
+	crc := uint32(0)
+	for _, v := range f.Enc {
+		if len(buf) == cap(buf) {
+			crc = crc32.Update(crc, crc32.IEEETable, buf)
+			buf = buf[0:0]
+		}
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+	}
+	for _, v := range f.Blocks {
+		if len(buf) == cap(buf) {
+			crc = crc32.Update(crc, crc32.IEEETable, buf)
+			buf = buf[0:0]
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(v))
+	}
+	crc = crc32.Update(crc, crc32.IEEETable, buf)
+	if f.Pad != 0 {
+		crc = crc32.Update(crc, crc32.IEEETable, binary.LittleEndian.AppendUint64(nil, uint64(f.Pad)))
+	}
+	if f.SetID != 0 {
+		crc = crc32.Update(crc, crc32.IEEETable, binary.LittleEndian.AppendUint64(nil, f.SetID))
+	}
+	return crc
+}
@@ -0,0 +1,121 @@
+package ida
+
+import "slices"
+
+// Decoder reconstructs data encoded with a fixed set of m encoding rows, such as the rows
+// assigned to particular storage nodes, inverting their matrix once, when the Decoder is made,
+// instead of once per object as [Reconstruct] must.
+// A Decoder is safe for concurrent use.
+type Decoder struct {
+	a    Matrix // the encoding rows, in order
+	ainv Matrix // their inverse, or nil if they are the identity matrix
+}
+
+// NewDecoder returns a Decoder for data encoded with the given m rows of length m.
+// It returns an error if the rows do not form a square matrix, or it is singular.
+func NewDecoder(rows [][]Field) (*Decoder, error) {
+	m := len(rows)
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	a := NewMatrix(m)
+	sys := true
+	for i, row := range rows {
+		if len(row) != m {
+			return nil, ErrInconsistentMatrix
+		}
+		a[i] = append([]Field(nil), row...)
+		sys = sys && sysrow(row) == i
+	}
+	if sys {
+		return &Decoder{a: a}, nil
+	}
+	ainv, err := a.Invert()
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{a: a, ainv: ainv}, nil
+}
+
+// M returns the number of rows, and so of fragments needed for reconstruction.
+func (dec *Decoder) M() int {
+	return len(dec.a)
+}
+
+// Reconstruct returns the data of length dlen encoded by fragments with dec's rows,
+// given their Enc values, in the same order as the rows.
+// Since only the Enc values are given, there is no Digest to check the result against.
+// It returns a [*FragmentError] with Reason ErrInconsistentFragment if the Enc values are not all the right length for dlen.
+func (dec *Decoder) Reconstruct(encCols [][]int, dlen int) ([]byte, error) {
+	m := len(dec.a)
+	if len(encCols) != m {
+		return nil, ErrTooFewFragments
+	}
+	if dlen < 0 {
+		return nil, ErrInconsistentFragment
+	}
+	fraglen := enclen(int64(dlen), m)
+	d := &decoder{m: m, fraglen: int(fraglen), frags: make([]*Frag, m), a: dec.a, ainv: dec.ainv, sys: dec.ainv == nil}
+	for j, enc := range encCols {
+		if int64(len(enc)) != fraglen {
+			return nil, &FragmentError{j, ErrInconsistentFragment}
+		}
+		d.frags[j] = &Frag{Len: int64(dlen), M: m, A: dec.a[j], Enc: enc}
+	}
+	out := make([]byte, dlen)
+	if err := d.decode(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DecodePlan is the first phase of reconstruction split in two, as by [PrepareDecode]:
+// the fragments to be used have been chosen and checked, and their matrix inverted,
+// leaving only the data to be produced by Decode.
+type DecodePlan struct {
+	d *decoder
+}
+
+// PrepareDecode chooses m consistent fragments of frags with independent rows, as [Reconstruct] would,
+// and inverts their matrix, returning a DecodePlan to produce the data, or the error Reconstruct would return
+// if the fragments cannot be decoded at all. Only the fragments named by the plan's Used method
+// are needed by Decode, so fetches of any others can be cancelled.
+func PrepareDecode(frags []*Frag) (*DecodePlan, error) {
+	d, err := newDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	return &DecodePlan{d: d}, nil
+}
+
+// Used returns the indices in the set given to PrepareDecode of the m fragments that Decode will use.
+func (p *DecodePlan) Used() []int {
+	return slices.Clone(p.d.used)
+}
+
+// Indexes returns the Index values of the m fragments that Decode will use, in the same order as Used.
+func (p *DecodePlan) Indexes() []int {
+	idx := make([]int, len(p.d.frags))
+	for i, f := range p.d.frags {
+		idx[i] = f.Index
+	}
+	return idx
+}
+
+// Decode returns the data encoded by the plan's fragments, checked against their Digest if they have one.
+// The fragments must not have been changed since PrepareDecode.
+func (p *DecodePlan) Decode() ([]byte, error) {
+	d := p.d
+	dlen, err := d.size()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, dlen)
+	if err := d.decode(out); err != nil {
+		return nil, err
+	}
+	if err := d.check(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,90 @@
+package ida
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	rows := Matrix{{1, 2, 3}, {4, 5, 6}, {7, 8, 10}}
+	dec, err := NewDecoder(rows)
+	if err != nil {
+		t.Fatalf("NewDecoder: %v", err)
+	}
+	sdec, err := NewDecoder(IdentityMatrix(3))
+	if err != nil {
+		t.Fatalf("NewDecoder(identity): %v", err)
+	}
+	for _, size := range []int{0, 1, 6, 7, 1000} {
+		data := make([]byte, size)
+		rand.Read(data)
+		for _, d := range []*Decoder{dec, sdec} {
+			encs := make([][]int, 3)
+			for j, a := range d.a {
+				f, err := FragmentWith(data, a)
+				if err != nil {
+					t.Fatalf("FragmentWith: %v", err)
+				}
+				encs[j] = f.Enc
+			}
+			out, err := d.Reconstruct(encs, size)
+			if err != nil {
+				t.Errorf("size %d: %v", size, err)
+				continue
+			}
+			if !bytes.Equal(out, data) {
+				t.Errorf("size %d: data differs", size)
+			}
+		}
+	}
+	if _, err := dec.Reconstruct([][]int{{1}, {2}}, 6); err != ErrTooFewFragments {
+		t.Errorf("two columns: want %v got %v", ErrTooFewFragments, err)
+	}
+	if _, err := dec.Reconstruct([][]int{{1}, {2}, {3}}, 7); !errors.Is(err, ErrInconsistentFragment) {
+		t.Errorf("short columns: want %v got %v", ErrInconsistentFragment, err)
+	}
+	if _, err := NewDecoder(Matrix{{1, 2}, {2, 4}}); err == nil {
+		t.Errorf("NewDecoder of singular matrix: no error")
+	}
+	if _, err := NewDecoder(Matrix{{1, 2}}); err != ErrInconsistentMatrix {
+		t.Errorf("NewDecoder of non-square matrix: want %v got %v", ErrInconsistentMatrix, err)
+	}
+}
+
+func TestDecodePlan(t *testing.T) {
+	data := []byte("cancel the fetches that are not needed")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	set := []*Frag{nil, frags[4], frags[1], frags[4], frags[5], frags[0]}
+	p, err := PrepareDecode(set)
+	if err != nil {
+		t.Fatalf("PrepareDecode: %v", err)
+	}
+	used := p.Used()
+	if want := []int{1, 2, 4}; !slices.Equal(used, want) {
+		t.Errorf("Used: want %v got %v", want, used)
+	}
+	for i, idx := range p.Indexes() {
+		if want := set[used[i]].Index; idx != want {
+			t.Errorf("Indexes[%d]: want %d got %d", i, want, idx)
+		}
+	}
+	for _, j := range []int{0, 3, 5} {
+		set[j] = nil // cancelled
+	}
+	zot, err := p.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Decode: want %q got %q", data, zot)
+	}
+	if _, err := PrepareDecode(frags[0:2]); err != ErrTooFewFragments {
+		t.Errorf("PrepareDecode of 2: want %v got %v", ErrTooFewFragments, err)
+	}
+}
@@ -0,0 +1,172 @@
+package ida
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A fragment directory holds each fragment of a set in its own file, frag.000, frag.001, ...,
+// in the binary encoding, and a text file, manifest, with lines giving the set's parameters:
+//
+//	ida manifest
+//	m 3
+//	n 5
+//	len 1234
+//	sha256 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//
+// The sha256 line is the fragments' Digest, and is absent if they have none.
+
+const manifestName = "manifest"
+
+var ErrBadManifest = errors.New("invalid fragment manifest")
+
+// manifest is the content of a fragment directory's manifest file.
+type manifest struct {
+	m      int
+	n      int
+	dlen   int64
+	digest []byte
+}
+
+// WriteFragments writes frags to directory dir, which is created if need be,
+// each fragment to its own file, with a manifest describing the set,
+// taking the parameters from the first fragment.
+func WriteFragments(dir string, frags []*Frag) error {
+	if len(frags) == 0 || frags[0] == nil {
+		return ErrTooFewFragments
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, f := range frags {
+		buf, err := f.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("frag.%03d", i)), buf, 0644); err != nil {
+			return err
+		}
+	}
+	mf := manifest{m: frags[0].M, n: len(frags), dlen: frags[0].Len, digest: frags[0].Digest}
+	return os.WriteFile(filepath.Join(dir, manifestName), mf.bytes(), 0644)
+}
+
+// ReadFragments returns the consistent set of fragments found in directory dir, as written by WriteFragments.
+// Fragment files that are missing, unreadable, truncated, or disagree with the manifest are skipped,
+// with a message logged giving the reason; so is the manifest itself if it is missing or invalid,
+// leaving the choice to [Consistent].
+func ReadFragments(dir string) ([]*Frag, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "frag.*"))
+	if err != nil {
+		return nil, err
+	}
+	var mf *manifest
+	if buf, err := os.ReadFile(filepath.Join(dir, manifestName)); err != nil {
+		log.Printf("ida: skipping manifest: %v", err)
+	} else if mf, err = parseManifest(buf); err != nil {
+		log.Printf("ida: skipping %s: %v", filepath.Join(dir, manifestName), err)
+	}
+	var frags []*Frag
+	for _, name := range names {
+		buf, err := os.ReadFile(name)
+		if err != nil {
+			log.Printf("ida: skipping %s: %v", name, err)
+			continue
+		}
+		f := new(Frag)
+		if err := f.UnmarshalBinary(buf); err != nil {
+			log.Printf("ida: skipping %s: %v", name, err)
+			continue
+		}
+		if mf != nil && (f.M != mf.m || f.Len != mf.dlen || !bytes.Equal(f.Digest, mf.digest)) {
+			log.Printf("ida: skipping %s: parameters differ from manifest", name)
+			continue
+		}
+		frags = append(frags, f)
+	}
+	if mf != nil && len(frags) < mf.n {
+		log.Printf("ida: %s: %d of %d fragments read", dir, len(frags), mf.n)
+	}
+	return Consistent(frags)
+}
+
+// ReadFragmentsFS returns the fragments decoded from the files in fsys matching pattern, as for [fs.Glob],
+// each holding a fragment in the binary encoding.
+// A file that cannot be read or decoded does not stop the rest being read: ReadFragmentsFS returns
+// the fragments it could decode, in the order of the file names, together with
+// the errors for those it could not, combined by [errors.Join] (or nil if there were none).
+// The fragments are not checked for consistency; see [Consistent].
+func ReadFragmentsFS(fsys fs.FS, pattern string) ([]*Frag, error) {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	var frags []*Frag
+	var errs []error
+	for _, name := range names {
+		buf, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f := new(Frag)
+		if err := f.UnmarshalBinary(buf); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		frags = append(frags, f)
+	}
+	return frags, errors.Join(errs...)
+}
+
+func (mf *manifest) bytes() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "ida manifest\nm %d\nn %d\nlen %d\n", mf.m, mf.n, mf.dlen)
+	if mf.digest != nil {
+		fmt.Fprintf(&b, "sha256 %x\n", mf.digest)
+	}
+	return b.Bytes()
+}
+
+func parseManifest(buf []byte) (*manifest, error) {
+	sc := bufio.NewScanner(bytes.NewReader(buf))
+	if !sc.Scan() || sc.Text() != "ida manifest" {
+		return nil, fmt.Errorf("%w: bad header", ErrBadManifest)
+	}
+	mf := &manifest{m: -1, n: -1, dlen: -1}
+	for sc.Scan() {
+		key, val, ok := strings.Cut(sc.Text(), " ")
+		if !ok {
+			return nil, fmt.Errorf("%w: bad line %q", ErrBadManifest, sc.Text())
+		}
+		var err error
+		switch key {
+		case "m":
+			mf.m, err = strconv.Atoi(val)
+		case "n":
+			mf.n, err = strconv.Atoi(val)
+		case "len":
+			mf.dlen, err = strconv.ParseInt(val, 10, 64)
+		case "sha256":
+			mf.digest, err = hex.DecodeString(val)
+		default:
+			// ignore unknown keys, for later additions
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrBadManifest, key, err)
+		}
+	}
+	if mf.m < 1 || mf.n < mf.m || mf.dlen < 0 {
+		return nil, fmt.Errorf("%w: missing or invalid parameters", ErrBadManifest)
+	}
+	return mf, nil
+}
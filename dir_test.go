@@ -0,0 +1,107 @@
+package ida
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriteReadFragments(t *testing.T) {
+	data := make([]byte, 5000)
+	rand.Read(data)
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "obj")
+	if err := WriteFragments(dir, frags); err != nil {
+		t.Fatalf("WriteFragments: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "frag.001")); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "frag.004")
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, buf[0:len(buf)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFragments(dir)
+	if err != nil {
+		t.Fatalf("ReadFragments: %v", err)
+	}
+	if len(got) != 4 {
+		t.Errorf("ReadFragments: want 4 fragments got %d", len(got))
+	}
+	zot, err := Reconstruct(got)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: data differs")
+	}
+
+	// without a manifest, Consistent has the last word
+	if err := os.Remove(filepath.Join(dir, manifestName)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadFragments(dir); err != nil || len(got) != 4 {
+		t.Errorf("ReadFragments without manifest: got %d fragments, %v", len(got), err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	mf := manifest{m: 3, n: 5, dlen: 1 << 40, digest: []byte{1, 2, 0xFF}}
+	got, err := parseManifest(mf.bytes())
+	if err != nil {
+		t.Fatalf("parseManifest: %v", err)
+	}
+	if got.m != mf.m || got.n != mf.n || got.dlen != mf.dlen || !bytes.Equal(got.digest, mf.digest) {
+		t.Errorf("parseManifest: want %+v got %+v", mf, *got)
+	}
+	for _, s := range []string{"", "manifest\n", "ida manifest\nm 3\n", "ida manifest\nm x\nn 5\nlen 1\n", "ida manifest\nm 3 n 5\n"} {
+		if _, err := parseManifest([]byte(s)); !errors.Is(err, ErrBadManifest) {
+			t.Errorf("parseManifest(%q): want %v got %v", s, ErrBadManifest, err)
+		}
+	}
+}
+
+func TestReadFragmentsFS(t *testing.T) {
+	data := []byte("fragments in an abstract file system")
+	frags, err := Encode(data, 2, 4)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	fsys := fstest.MapFS{}
+	for i, f := range frags {
+		buf, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[fmt.Sprintf("obj/frag.%d", i)] = &fstest.MapFile{Data: buf}
+	}
+	fsys["obj/frag.1"].Data = fsys["obj/frag.1"].Data[0:10]
+	fsys["obj/frag.3"].Data = []byte("rubbish")
+	fsys["obj/other"] = &fstest.MapFile{Data: []byte("ignored")}
+	got, err := ReadFragmentsFS(fsys, "obj/frag.*")
+	if len(got) != 2 || got[0].Index != 1 || got[1].Index != 3 {
+		t.Errorf("ReadFragmentsFS: want fragments 1 and 3, got %d", len(got))
+	}
+	if !errors.Is(err, ErrBadEncoding) || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Errorf("ReadFragmentsFS: want two errors, got %v", err)
+	}
+	zot, err := Reconstruct(got)
+	if err != nil || !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q, %v", data, zot, err)
+	}
+	if _, err := ReadFragmentsFS(fsys, "["); err == nil {
+		t.Errorf("ReadFragmentsFS bad pattern: no error")
+	}
+}
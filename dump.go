@@ -0,0 +1,105 @@
+package ida
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// summaryWords is the number of leading values of A and Enc shown by Frag.String.
+const summaryWords = 8
+
+// String returns a one-line summary of f, for logs: its Index, Len, M and A,
+// and the first few Enc values with the CRC-32 of all of them, as computed by Dump.
+// Use Dump to see everything.
+func (f *Frag) String() string {
+	if f == nil {
+		return "frag <nil>"
+	}
+	var sb strings.Builder
+	if f.Index != 0 {
+		fmt.Fprintf(&sb, "frag %d: ", f.Index)
+	} else {
+		sb.WriteString("frag: ")
+	}
+	fmt.Fprintf(&sb, "Len %d M %d A ", f.Len, f.M)
+	writeWords(&sb, f.A, summaryWords)
+	sb.WriteString(" Enc ")
+	writeWords(&sb, f.Enc, summaryWords)
+	fmt.Fprintf(&sb, " (%d words, crc %08x)", len(f.Enc), encsum(f.Enc))
+	return sb.String()
+}
+
+// Dump writes every field of f to w, one per line, with A and Enc in full, eight values to a line.
+func (f *Frag) Dump(w io.Writer) {
+	if f == nil {
+		fmt.Fprintln(w, "frag <nil>")
+		return
+	}
+	fmt.Fprintf(w, "Index\t%d\n", f.Index)
+	if f.SetID != 0 {
+		fmt.Fprintf(w, "SetID\t%016x\n", f.SetID)
+	}
+	fmt.Fprintf(w, "Len\t%d\n", f.Len)
+	fmt.Fprintf(w, "M\t%d\n", f.M)
+	fmt.Fprintf(w, "CRC\t%08x", f.CRC)
+	switch {
+	case f.CRC == 0:
+		fmt.Fprint(w, " (none)")
+	case !f.Verify():
+		fmt.Fprintf(w, " (bad: contents give %08x)", f.checksum())
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Digest\t%x\n", f.Digest)
+	if f.Blocks != nil {
+		fmt.Fprintf(w, "Blocks\t%v\n", f.Blocks)
+	}
+	if f.MAC != nil {
+		fmt.Fprintf(w, "MAC\t%x\n", f.MAC)
+	}
+	if f.Pad != 0 {
+		fmt.Fprintf(w, "Pad\t%d\n", f.Pad)
+	}
+	fmt.Fprintf(w, "A\t%d values\n", len(f.A))
+	writeLines(w, f.A)
+	fmt.Fprintf(w, "Enc\t%d values, crc %08x\n", len(f.Enc), encsum(f.Enc))
+	writeLines(w, f.Enc)
+}
+
+// writeWords writes the first max values of v to sb in brackets, with an ellipsis if there are more.
+func writeWords[T Field | int](sb *strings.Builder, v []T, max int) {
+	sb.WriteByte('[')
+	for i, x := range v {
+		if i == max {
+			sb.WriteString(" ...")
+			break
+		}
+		if i != 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprint(sb, x)
+	}
+	sb.WriteByte(']')
+}
+
+// writeLines writes the values of v to w, eight to a line, each line indented and prefixed by the index of its first value.
+func writeLines[T Field | int](w io.Writer, v []T) {
+	for i := 0; i < len(v); i += 8 {
+		fmt.Fprintf(w, "\t%d:", i)
+		for _, x := range v[i:min(i+8, len(v))] {
+			fmt.Fprintf(w, " %d", x)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// encsum returns the CRC-32 (IEEE) of enc as little-endian 32-bit values.
+func encsum(enc []int) uint32 {
+	buf := make([]byte, 0, 4*len(enc))
+	for _, v := range enc {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+	}
+	return crc32.ChecksumIEEE(buf)
+}
@@ -0,0 +1,53 @@
+package ida
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFragString(t *testing.T) {
+	f := newFrag(6, []Field{1, 2}, []int{3, 4}, nil)
+	f.Index = 5
+	want := fmt.Sprintf("frag 5: Len 6 M 2 A [1 2] Enc [3 4] (2 words, crc %08x)", encsum(f.Enc))
+	if s := f.String(); s != want {
+		t.Errorf("String: want %q got %q", want, s)
+	}
+	frags, err := Encode(make([]byte, 1000), 10, 10)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	s := frags[0].String()
+	if strings.Count(s, " ...") != 2 || len(s) > 200 {
+		t.Errorf("String of large fragment not summarised: %q", s)
+	}
+	if s := (*Frag)(nil).String(); s != "frag <nil>" {
+		t.Errorf("nil: got %q", s)
+	}
+}
+
+func TestFragDump(t *testing.T) {
+	frags, err := Encode([]byte("a fragment in full detail"), 3, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	f := frags[1]
+	var buf bytes.Buffer
+	f.Dump(&buf)
+	out := buf.String()
+	for _, want := range []string{"Index\t2\n", "Len\t25\n", "M\t3\n", "\t0: ", "Enc\t5 values"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Dump: missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "bad") {
+		t.Errorf("Dump: good CRC reported bad:\n%s", out)
+	}
+	f.Enc[0]++
+	buf.Reset()
+	f.Dump(&buf)
+	if !strings.Contains(buf.String(), "(bad: ") {
+		t.Errorf("Dump: bad CRC not reported:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,176 @@
+package ida
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// Encoder produces fragments with given parameters, drawing the encoding rows
+// from its own source of random numbers.
+// An Encoder with its own source is not safe for concurrent use, nor is one building fragments with Append.
+type Encoder struct {
+
+	// M is the minimum number of fragments needed for reconstruction.
+	M int
+
+	// N is the number of fragments made by Append and Finish.
+	N int
+
+	// Field, if not nil, is the field in which fragments are encoded, instead of Z(Prime),
+	// and they must then be reconstructed by [ReconstructIn] with the same field.
+	Field Arithmetic
+
+	// Progress, if not nil, is called by FragmentStream after each block is encoded,
+	// with the number of bytes done so far, and -1 for the total, which is not known in advance.
+	Progress func(done, total int64)
+
+	// Independent, if true, makes the encoding rows of each set of fragments rows of a Cauchy matrix
+	// with random nodes, instead of independent random rows, so that any m of the fragments
+	// are certain to be enough for reconstruction, not just very likely to be.
+	// The number of fragments plus M may then not exceed the order of the field.
+	Independent bool
+
+	rnd *rand.Rand // nil to use the default source
+	app *appender  // fragments being built by Append, or nil
+}
+
+// NewEncoder returns an Encoder producing fragments that require m of them for reconstruction,
+// with encoding rows drawn from src.
+// If src is nil, the Encoder uses the package's source, normally the default source of math/rand
+// (see [SetRandSource]), and is safe for concurrent use.
+func NewEncoder(m int, src rand.Source) *Encoder {
+	e := &Encoder{M: m}
+	if src != nil {
+		e.rnd = rand.New(src)
+	}
+	return e
+}
+
+// Fragment returns a Frag representing the encoded version of data,
+// as for the package function [Fragment], which panics if e.M < 1.
+func (e *Encoder) Fragment(data []byte) *Frag {
+	if e.M < 1 {
+		panic(ErrInvalidM)
+	}
+	a := e.row()
+	return newFrag(int64(len(data)), a, e.encode(data, a), digest(data))
+}
+
+// Encode returns n fragments of data with distinct encoding rows,
+// as for the package function [Encode].
+func (e *Encoder) Encode(data []byte, n int) ([]*Frag, error) {
+	if err := e.check(n); err != nil {
+		return nil, err
+	}
+	dg := digest(data)
+	frags := make([]*Frag, n)
+	rows, id := e.rows(n), e.setID()
+	for i, a := range rows {
+		frags[i] = newFrag(int64(len(data)), a, e.encode(data, a), slices.Clone(dg))
+		frags[i].member(i+1, id)
+	}
+	return frags, nil
+}
+
+// check returns ErrInvalidM or ErrInvalidN if e cannot make a set of n fragments.
+func (e *Encoder) check(n int) error {
+	if e.M < 1 {
+		return ErrInvalidM
+	}
+	if n < e.M || e.Independent && n+e.M > e.order() {
+		return ErrInvalidN
+	}
+	return nil
+}
+
+// order returns the number of elements in e's field.
+func (e *Encoder) order() int {
+	if e.Field != nil {
+		return e.Field.Order()
+	}
+	return Prime
+}
+
+// rows returns n distinct random encoding rows, any m of which are independent if e.Independent.
+// Otherwise, in Z(Prime), the rows are drawn again in the rare event that the first m are singular
+// (see [Matrix.IsInvertible]), so that at least those fragments can always be decoded.
+func (e *Encoder) rows(n int) [][]Field {
+	if e.Independent {
+		return e.cauchyRows(n)
+	}
+	rows := make([][]Field, n)
+	for i := range rows {
+		a := e.row()
+		for j := 0; j < i; j++ {
+			if slices.Equal(a, rows[j]) {
+				a = e.row() // roll again, and recheck all previous rows
+				j = -1
+			}
+		}
+		rows[i] = a
+	}
+	if e.Field == nil && n >= e.M && !Matrix(rows[0:e.M]).IsInvertible() {
+		return e.rows(n)
+	}
+	return rows
+}
+
+// cauchyRows returns n rows of the Cauchy matrix with elements 1/(x[i]-y[j]) in e's field,
+// where the x[i] and the m values y[j] are distinct random elements,
+// so that every m×m submatrix is invertible (see [CauchyMatrix]).
+func (e *Encoder) cauchyRows(n int) [][]Field {
+	sub, div := Field.sub, Field.div
+	if e.Field != nil {
+		sub, div = e.Field.Sub, e.Field.Div
+	}
+	src := randSource(e.rnd)
+	nodes := make([]Field, 0, n+e.M)
+	seen := make(map[Field]bool)
+	for len(nodes) < n+e.M {
+		if x := randField(src, uint32(e.order())) - 1; !seen[x] {
+			seen[x] = true
+			nodes = append(nodes, x)
+		}
+	}
+	xs, ys := nodes[0:n], nodes[n:]
+	rows := make([][]Field, n)
+	for i, x := range xs {
+		a := make([]Field, e.M)
+		for j, y := range ys {
+			a[j] = div(1, sub(x, y))
+		}
+		rows[i] = a
+	}
+	return rows
+}
+
+// setID returns a random SetID for a new set of fragments.
+func (e *Encoder) setID() uint64 {
+	return newSetID(e.rnd)
+}
+
+// newSetID returns a random non-zero SetID drawn from rnd, or from the package source if rnd is nil.
+func newSetID(rnd *rand.Rand) uint64 {
+	src := randSource(rnd)
+	for {
+		if id := uint64(src())<<32 | uint64(src()); id != 0 {
+			return id
+		}
+	}
+}
+
+// row returns a random encoding row in e's field.
+func (e *Encoder) row() []Field {
+	if e.Field != nil {
+		return randomVecIn(e.Field, e.rnd, e.M)
+	}
+	return randomVec(e.rnd, e.M)
+}
+
+// encode is encode in e's field.
+func (e *Encoder) encode(data []byte, a []Field) []int {
+	if e.Field != nil {
+		return encodeIn(e.Field, data, a)
+	}
+	return encode(data, a)
+}
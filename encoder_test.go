@@ -0,0 +1,106 @@
+package ida
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	data := []byte("Rabin's information dispersal algorithm")
+	e1 := NewEncoder(3, rand.NewSource(1))
+	e2 := NewEncoder(3, rand.NewSource(1))
+	var frags []*Frag
+	for i := 0; i < 5; i++ {
+		f1 := e1.Fragment(data)
+		f2 := e2.Fragment(data)
+		if !slices.Equal(f1.A, f2.A) || !slices.Equal(f1.Enc, f2.Enc) {
+			t.Errorf("fragment %d: same seed gave different fragments", i)
+		}
+		frags = append(frags, f1)
+	}
+	zot, err := Reconstruct(frags[2:])
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+}
+
+// rigged is a rand.Source that returns 0 for its first n values, then those of src.
+type rigged struct {
+	n   int
+	src rand.Source
+}
+
+func (r *rigged) Int63() int64 {
+	if r.n > 0 {
+		r.n--
+		return 0
+	}
+	return r.src.Int63()
+}
+
+func (r *rigged) Seed(seed int64) {
+	r.src.Seed(seed)
+}
+
+func TestDistinctRows(t *testing.T) {
+	data := []byte("no two fragments alike")
+	const m, n = 3, 6
+	for _, field := range []Arithmetic{nil, GF65536{}} {
+		e := NewEncoder(m, &rigged{n: 3 * m, src: rand.NewSource(1)}) // the first three rows are all ones
+		e.Field = field
+		frags, err := e.Encode(data, n)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		for i, f := range frags {
+			for _, f1 := range frags[0:i] {
+				if slices.Equal(f.A, f1.A) {
+					t.Errorf("%v: fragments %d and %d have the same row %v", field, f1.Index, f.Index, f.A)
+				}
+			}
+		}
+		if field == nil {
+			if zot, err := Reconstruct(frags[0:m]); err != nil || !bytes.Equal(zot, data) {
+				t.Errorf("Reconstruct: want %q got %q, %v", data, zot, err)
+			}
+		}
+	}
+}
+
+func TestIndependent(t *testing.T) {
+	data := []byte("any m of these will do, without fail")
+	const m, n = 4, 9
+	for _, field := range []Arithmetic{nil, GF65536{}} {
+		e := NewEncoder(m, rand.NewSource(1))
+		e.Field, e.Independent = field, true
+		frags, err := e.Encode(data, n)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		for _, s := range combinations(n, m) {
+			sel := make([]*Frag, m)
+			for i, j := range s {
+				sel[i] = frags[j]
+			}
+			var zot []byte
+			if field == nil {
+				zot, err = Reconstruct(sel)
+			} else {
+				zot, err = ReconstructIn(field, sel)
+			}
+			if err != nil || !bytes.Equal(zot, data) {
+				t.Errorf("%v: Reconstruct %v: want %q got %q, %v", field, s, data, zot, err)
+			}
+		}
+	}
+	e := NewEncoder(2, nil)
+	e.Field, e.Independent = GF65536{}, true
+	if _, err := e.Encode(data, 1<<16-1); err != ErrInvalidN {
+		t.Errorf("Encode with n+m > order: want %v got %v", ErrInvalidN, err)
+	}
+}
@@ -0,0 +1,288 @@
+package ida
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"slices"
+)
+
+var (
+	ErrNotPrime      = errors.New("field order is not prime")
+	ErrFieldTooSmall = errors.New("field order too small to hold two bytes per word")
+	ErrWordWidth     = errors.New("word width must be 1 to 4 bytes")
+)
+
+// FieldCtx is the field Z(p) for a prime p given at run time, instead of the fixed Prime used by the
+// package-level functions, with its own versions of the field operations, matrix inversion,
+// and fragment encoding and reconstruction.
+// Fragments encoded in one field must be reconstructed in the same field, and since Frag
+// does not record the field, that is the caller's responsibility.
+// (In particular, the package-level Consistent and Reconstruct will reject fragments
+// with values outside Z(Prime).)
+// Inverses are computed on demand, as for the idanotab build tag, so any prime can be used.
+// The data is packed WordBytes bytes to a word, so a larger field carries more data in each Enc value:
+// Enc has ceil(Len/(WordBytes×M)) values, instead of ceil(Len/2M) as in Z(Prime).
+type FieldCtx struct {
+	p uint64
+}
+
+// NewField returns a FieldCtx for the field Z(prime).
+// The data is packed at least two bytes to a word (see [FieldCtx.WordBytes]), so prime must be larger than 0xFFFF.
+func NewField(prime uint32) (*FieldCtx, error) {
+	if prime <= 0xFFFF {
+		return nil, ErrFieldTooSmall
+	}
+	if !big.NewInt(int64(prime)).ProbablyPrime(0) {
+		return nil, ErrNotPrime
+	}
+	return &FieldCtx{p: uint64(prime)}, nil
+}
+
+// Prime returns the order of the field.
+func (fc *FieldCtx) Prime() uint32 {
+	return uint32(fc.p)
+}
+
+// Add returns a+b in the field.
+func (fc *FieldCtx) Add(a, b Field) Field {
+	return Field((uint64(a) + uint64(b)) % fc.p)
+}
+
+// Sub returns a-b in the field.
+func (fc *FieldCtx) Sub(a, b Field) Field {
+	return Field((uint64(a) + fc.p - uint64(b)) % fc.p)
+}
+
+// Mul returns a×b in the field.
+func (fc *FieldCtx) Mul(a, b Field) Field {
+	return Field((uint64(a) * uint64(b)) % fc.p)
+}
+
+// Div returns a/b in the field. Division by zero is defined to yield zero.
+func (fc *FieldCtx) Div(a, b Field) Field {
+	return fc.Mul(a, fc.Inverse(b))
+}
+
+// Inverse returns the multiplicative inverse of a, or zero if a is zero.
+func (fc *FieldCtx) Inverse(a Field) Field {
+	r := Field(1)
+	for e := fc.p - 2; e != 0; e >>= 1 {
+		if e&1 != 0 {
+			r = fc.Mul(r, a)
+		}
+		a = fc.Mul(a, a)
+	}
+	return r
+}
+
+// Invert returns the inverse of a in the field, leaving a untouched, as Matrix.Invert does in Z(Prime).
+func (fc *FieldCtx) Invert(a Matrix) (Matrix, error) {
+	return invertIn(fc, a)
+}
+
+// Fragment returns a Frag representing the encoded version of data in the field,
+// as the package-level [Fragment] does in Z(Prime). It panics if m < 1.
+func (fc *FieldCtx) Fragment(data []byte, m int) *Frag {
+	if m < 1 {
+		panic(ErrInvalidM)
+	}
+	a := randomVecIn(fc, nil, m)
+	return newFrag(int64(len(data)), a, encodeIn(fc, data, a), digest(data))
+}
+
+// Reconstruct returns the data encoded in the field by the first m of the given consistent set of fragments,
+// as the package-level [Reconstruct] does in Z(Prime).
+func (fc *FieldCtx) Reconstruct(frags []*Frag) ([]byte, error) {
+	return ReconstructIn(fc, frags)
+}
+
+// Order returns the number of elements in the field.
+func (fc *FieldCtx) Order() int {
+	return int(fc.p)
+}
+
+// WordBytes returns the number of bytes that fit in a word of the field, floor(log₂₅₆(p)):
+// 2 for Prime and other primes up to 2^24, and 3 for larger ones.
+// Fragment and Reconstruct pack the data that many bytes to a word.
+func (fc *FieldCtx) WordBytes() int {
+	return wordBytes(fc.Order())
+}
+
+// wordBytes returns the number of bytes that fit in a word of a field of the given order, floor(log₂₅₆(order)).
+func wordBytes(order int) int {
+	w := 0
+	for v := uint64(256); v <= uint64(order); v *= 256 {
+		w++
+	}
+	return w
+}
+
+// PackWords returns data packed as elements of the field, WordBytes to a word, as by [PackWordsWidth],
+// as Fragment packs it.
+func (fc *FieldCtx) PackWords(data []byte) []Field {
+	return PackWordsWidth(data, fc.WordBytes())
+}
+
+// UnpackWords returns the first dlen bytes of words unpacked as by fc.PackWords.
+func (fc *FieldCtx) UnpackWords(words []Field, dlen int) []byte {
+	return UnpackWordsWidth(words, dlen, fc.WordBytes())
+}
+
+// ReconstructIn returns the data encoded in field ar by m of the given set of fragments,
+// as the package-level [Reconstruct] does in Z(Prime).
+// As there, nil fragments are ignored, and m, Len, the length of Enc, the Digest and the SetID are decided by majority vote;
+// the first m fragments that agree with the vote are used.
+// A fragment with a value in A or Enc that is not an element of the field gives ErrInvalidValue.
+func ReconstructIn(ar Arithmetic, frags []*Frag) ([]byte, error) {
+	width := wordBytes(ar.Order())
+	b, err := vote(frags, width)
+	if err != nil {
+		return nil, err
+	}
+	m := b.m
+	fraglen := b.fraglen
+	dlen := b.dlen
+	used := make([]*Frag, m)
+	a := NewMatrix(m)
+	for j := range a {
+		i := b.idx[j]
+		f := frags[i]
+		if err := b.check(f, i); err != nil {
+			return nil, err
+		}
+		if string(f.Digest) != b.digest {
+			return nil, &FragmentError{i, ErrInconsistentFragment}
+		}
+		if outside(ar, f) {
+			return nil, &FragmentError{i, ErrInvalidValue}
+		}
+		used[j] = f
+		a[j] = slices.Clone(f.A)
+	}
+	ainv, err := invertIn(ar, a)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decoding matrix: %w", err)
+	}
+	if dlen < 0 || dlen > math.MaxInt {
+		return nil, ErrDataTooLarge
+	}
+	out := make([]byte, dlen)
+	o := int64(0)
+	for k := 0; k < fraglen; k++ {
+		for i := 0; i < m; i++ {
+			w := zero
+			for j := 0; j < m; j++ {
+				w = ar.Add(w, ar.Mul(Field(used[j].Enc[k]), ainv[i][j]))
+			}
+			if w>>(8*width) != 0 {
+				return nil, &CorruptOutputError{k, i}
+			}
+			o += int64(unpackWidth(out[o:], []Field{w}, width))
+		}
+	}
+	if b.digest != "" && string(digest(out)) != b.digest {
+		return nil, ErrDigestMismatch
+	}
+	return out, nil
+}
+
+// outside returns true if any value in f's A or Enc is not an element of field ar,
+// which the field's operations need not handle, GF65536's tables in particular.
+func outside(ar Arithmetic, f *Frag) bool {
+	n := uint64(ar.Order())
+	for _, v := range f.A {
+		if uint64(v) >= n {
+			return true
+		}
+	}
+	for _, v := range f.Enc {
+		if v < 0 || uint64(v) >= n {
+			return true
+		}
+	}
+	return false
+}
+
+// Arithmetic is the arithmetic of a finite field whose elements are the values 0 to Order()-1,
+// for the versions of the fragment operations that work in any field, such as [FieldCtx] and [GF65536].
+// Div by zero must yield zero.
+type Arithmetic interface {
+	Add(a, b Field) Field
+	Sub(a, b Field) Field
+	Mul(a, b Field) Field
+	Div(a, b Field) Field
+	Order() int
+}
+
+// randomVecIn returns a slice of length m containing random non-zero elements of field ar.
+func randomVecIn(ar Arithmetic, rnd *rand.Rand, m int) []Field {
+	a := make([]Field, m)
+	src := randSource(rnd)
+	for i := range a {
+		a[i] = randField(src, uint32(ar.Order()-1))
+	}
+	return a
+}
+
+// encodeIn is encode in the field ar.
+func encodeIn(ar Arithmetic, data []byte, a []Field) []int {
+	if _, ok := ar.(GF65536); ok {
+		return gfEncode(data, a)
+	}
+	m := len(a)
+	width := wordBytes(ar.Order())
+	nw := (len(data) + width - 1) / width
+	f := make([]int, enclenWidth(int64(len(data)), m, width))
+	i := 0
+	for o := range f {
+		c := zero
+		for j := 0; j < m && i < nw; j++ {
+			c = ar.Add(c, ar.Mul(wordWidth(data, i, width), a[j]))
+			i++
+		}
+		f[o] = int(c)
+	}
+	return f
+}
+
+// invertIn is Matrix.Invert in the field ar.
+func invertIn(ar Arithmetic, a Matrix) (Matrix, error) {
+	m := len(a)
+	out := make(Matrix, m)
+	for r := 0; r < m; r++ {
+		if len(a[r]) != m {
+			return nil, ErrNonSquare
+		}
+		out[r] = make([]Field, m*2)
+		copy(out[r], a[r])
+		out[r][m+r] = 1
+	}
+	for r := 0; r < m; r++ {
+		for p := r + 1; out[r][r] == 0 && p < m; p++ {
+			if out[p][r] != 0 {
+				out[r], out[p] = out[p], out[r]
+			}
+		}
+		x := out[r][r]
+		if x == 0 {
+			return nil, ErrZeroPivot
+		}
+		for c := 0; c < 2*m; c++ {
+			out[r][c] = ar.Div(out[r][c], x)
+		}
+		for r1 := 0; r1 < m; r1++ {
+			if y := out[r1][r]; r1 != r && y != 0 {
+				for c := 0; c < 2*m; c++ {
+					out[r1][c] = ar.Sub(out[r1][c], ar.Mul(y, out[r][c]))
+				}
+			}
+		}
+	}
+	for r := 0; r < m; r++ {
+		out[r] = out[r][m:]
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package ida
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestNewField(t *testing.T) {
+	for _, p := range []uint32{2, 257, 65521} {
+		if _, err := NewField(p); err != ErrFieldTooSmall {
+			t.Errorf("NewField(%d): want %v got %v", p, ErrFieldTooSmall, err)
+		}
+	}
+	for _, p := range []uint32{65541, 1 << 24, 4294967295} {
+		if _, err := NewField(p); err != ErrNotPrime {
+			t.Errorf("NewField(%d): want %v got %v", p, ErrNotPrime, err)
+		}
+	}
+	for _, p := range []uint32{65537, 65539, 16777259, 4294967291} {
+		fc, err := NewField(p)
+		if err != nil {
+			t.Errorf("NewField(%d): %v", p, err)
+			continue
+		}
+		for _, a := range []Field{1, 2, 0xFFFF, Field(p - 1)} {
+			if r := fc.Mul(a, fc.Inverse(a)); r != 1 {
+				t.Errorf("Z(%d): %d × 1/%d: want 1 got %d", p, a, a, r)
+			}
+			if r := fc.Add(a, fc.Sub(0, a)); r != 0 {
+				t.Errorf("Z(%d): %d + -%d: want 0 got %d", p, a, a, r)
+			}
+		}
+		if p == Prime {
+			all2(t, "Z(Prime)", func(a, b Field) bool {
+				return fc.Add(a, b) == a.add(b) && fc.Sub(a, b) == a.sub(b) && fc.Mul(a, b) == a.mul(b) && fc.Div(a, b) == a.div(b)
+			})
+		}
+		data := []byte("the same old data in a brave new field")
+		var frags []*Frag
+		for i := 0; i < 4; i++ {
+			frags = append(frags, fc.Fragment(data, 4))
+		}
+		zot, err := fc.Reconstruct(frags)
+		if err != nil {
+			t.Errorf("Z(%d): Reconstruct: %v", p, err)
+			continue
+		}
+		if !bytes.Equal(zot, data) {
+			t.Errorf("Z(%d): Reconstruct: want %q got %q", p, data, zot)
+		}
+	}
+}
+
+func TestWordBytes(t *testing.T) {
+	for _, c := range []struct {
+		p     uint32
+		width int
+	}{
+		{65537, 2}, {65539, 2}, {16777213, 2}, {16777259, 3}, {4294967291, 3},
+	} {
+		fc, err := NewField(c.p)
+		if err != nil {
+			t.Fatalf("NewField(%d): %v", c.p, err)
+		}
+		if w := fc.WordBytes(); w != c.width {
+			t.Errorf("Z(%d): WordBytes: want %d got %d", c.p, c.width, w)
+		}
+		data := []byte("three bytes to a word, or two")
+		words := fc.PackWords(data)
+		if len(words) != (len(data)+c.width-1)/c.width {
+			t.Errorf("Z(%d): PackWords: %d words for %d bytes", c.p, len(words), len(data))
+		}
+		for _, w := range words {
+			if uint32(w) >= c.p {
+				t.Errorf("Z(%d): PackWords: word %#x not in the field", c.p, w)
+			}
+		}
+		if out := fc.UnpackWords(words, len(data)); !bytes.Equal(out, data) {
+			t.Errorf("Z(%d): UnpackWords: want %q got %q", c.p, data, out)
+		}
+	}
+}
+
+func TestFieldCtxWordBytes(t *testing.T) {
+	data := make([]byte, 200)
+	rand.New(rand.NewSource(1)).Read(data)
+	for _, p := range []uint32{65539, 16777259, 4294967291} {
+		fc, err := NewField(p)
+		if err != nil {
+			t.Fatalf("NewField(%d): %v", p, err)
+		}
+		width := fc.WordBytes()
+		for _, m := range []int{1, 2, 3, 5} {
+			for n := 0; n <= len(data); n += 1 + n/8 {
+				var frags []*Frag
+				for i := 0; i < m; i++ {
+					frags = append(frags, fc.Fragment(data[0:n], m))
+				}
+				// each Enc value carries m words of width bytes
+				if want := ((n+width-1)/width + m - 1) / m; len(frags[0].Enc) != want {
+					t.Fatalf("Z(%d), m=%d, length %d: want %d Enc values got %d", p, m, n, want, len(frags[0].Enc))
+				}
+				zot, err := fc.Reconstruct(frags)
+				if err != nil || !bytes.Equal(zot, data[0:n]) {
+					t.Fatalf("Z(%d), m=%d, length %d: round trip failed: %v", p, m, n, err)
+				}
+			}
+		}
+		// the Encoder's block-wise encodings concatenate at whole columns of the wider words
+		e := NewEncoder(3, rand.NewSource(2))
+		e.Field = fc
+		long := make([]byte, 3*streamBlock+7)
+		rand.New(rand.NewSource(3)).Read(long)
+		frags, err := e.FragmentStream(bytes.NewReader(long), 4)
+		if err != nil {
+			t.Fatalf("Z(%d): FragmentStream: %v", p, err)
+		}
+		if zot, err := ReconstructIn(fc, frags[1:]); err != nil || !bytes.Equal(zot, long) {
+			t.Errorf("Z(%d): FragmentStream round trip failed: %v", p, err)
+		}
+		e.N = 4
+		for _, b := range [][]byte{data[0:7], data[7:8], data[8:100]} {
+			if err := e.Append(b); err != nil {
+				t.Fatalf("Z(%d): Append: %v", p, err)
+			}
+		}
+		if zot, err := ReconstructIn(fc, e.Finish()[0:3]); err != nil || !bytes.Equal(zot, data[0:100]) {
+			t.Errorf("Z(%d): Append round trip failed: %v", p, err)
+		}
+	}
+}
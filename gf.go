@@ -0,0 +1,91 @@
+package ida
+
+import "sync"
+
+// gfPoly is the primitive polynomial x¹⁶+x¹²+x³+x+1 defining GF(2¹⁶).
+const gfPoly = 0x1100B
+
+// GF65536 is the field GF(2¹⁶), an alternative to Z(Prime) for use with [Encoder] and [ReconstructIn].
+// Addition is exclusive-or and multiplication uses log and antilog tables built on first use,
+// so encoding needs no division, and every two-byte word is a field element.
+// Fragments encoded in GF65536 must be reconstructed by ReconstructIn(GF65536{}, ...).
+type GF65536 struct{}
+
+var (
+	gfOnce sync.Once
+	gfLog  []uint16 // gfLog[x] is the discrete log of x≠0 to base 2
+	gfExp  []uint16 // gfExp[i] is 2↑i, doubled in length so a sum of two logs needs no reduction
+)
+
+func gfTables() {
+	gfLog = make([]uint16, 1<<16)
+	gfExp = make([]uint16, 2*0xFFFF)
+	x := 1
+	for i := 0; i < 0xFFFF; i++ {
+		gfExp[i] = uint16(x)
+		gfExp[i+0xFFFF] = uint16(x)
+		gfLog[x] = uint16(i)
+		x <<= 1
+		if x&0x10000 != 0 {
+			x ^= gfPoly
+		}
+	}
+}
+
+// Order returns the number of elements in the field.
+func (GF65536) Order() int {
+	return 1 << 16
+}
+
+// Add returns a+b in the field.
+func (GF65536) Add(a, b Field) Field {
+	return a ^ b
+}
+
+// Sub returns a-b in the field, which is the same as a+b.
+func (GF65536) Sub(a, b Field) Field {
+	return a ^ b
+}
+
+// Mul returns a×b in the field.
+func (GF65536) Mul(a, b Field) Field {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	gfOnce.Do(gfTables)
+	return Field(gfExp[int(gfLog[a])+int(gfLog[b])])
+}
+
+// Div returns a/b in the field. Division by zero is defined to yield zero.
+func (GF65536) Div(a, b Field) Field {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	gfOnce.Do(gfTables)
+	return Field(gfExp[int(gfLog[a])+0xFFFF-int(gfLog[b])])
+}
+
+// gfEncode is encode in GF65536, with the logs of a computed once.
+func gfEncode(data []byte, a []Field) []int {
+	gfOnce.Do(gfTables)
+	m := len(a)
+	la := make([]int, m)
+	for j, x := range a {
+		la[j] = int(gfLog[x])
+	}
+	nw, fl := FragGeometry(len(data), m)
+	f := make([]int, fl)
+	i := 0
+	for o := range f {
+		c := uint16(0)
+		for j := 0; j < m && i < nw; j++ {
+			b := uint16(word(data, i))
+			i++
+			if b != 0 && a[j] != 0 {
+				c ^= gfExp[int(gfLog[b])+la[j]]
+			}
+		}
+		f[o] = int(c)
+	}
+	return f
+}
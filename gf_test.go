@@ -0,0 +1,146 @@
+package ida
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestGF65536(t *testing.T) {
+	var gf GF65536
+	gfOnce.Do(gfTables)
+	seen := make([]bool, 1<<16)
+	for i := 0; i < 0xFFFF; i++ {
+		x := gfExp[i]
+		if x == 0 || seen[x] {
+			t.Fatalf("2↑%d = %d: generator does not have order 65535", i, x)
+		}
+		seen[x] = true
+	}
+	for _, a := range []Field{1, 2, 3, 0x100, 0x8000, 0xFFFF} {
+		for _, b := range []Field{1, 5, 0x1234, 0xFFFF} {
+			p := gf.Mul(a, b)
+			if p != gf.Mul(b, a) {
+				t.Errorf("%d×%d not commutative", a, b)
+			}
+			if r := gf.Div(p, b); r != a {
+				t.Errorf("%d×%d/%d: want %d got %d", a, b, b, a, r)
+			}
+			// check against carry-less multiplication reduced by the polynomial
+			x := 0
+			for i := 15; i >= 0; i-- {
+				x <<= 1
+				if x&0x10000 != 0 {
+					x ^= gfPoly
+				}
+				if b&(1<<i) != 0 {
+					x ^= int(a)
+				}
+			}
+			if p != Field(x) {
+				t.Errorf("%d×%d: want %d got %d", a, b, x, p)
+			}
+		}
+		if gf.Mul(a, 0) != 0 || gf.Div(a, 0) != 0 || gf.Add(a, a) != 0 {
+			t.Errorf("%d: zero rules fail", a)
+		}
+	}
+}
+
+func TestEncoderGF65536(t *testing.T) {
+	data := make([]byte, 10001)
+	rand.New(rand.NewSource(1)).Read(data)
+	e := NewEncoder(5, rand.NewSource(2))
+	e.Field = GF65536{}
+	frags, err := e.Encode(data, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sel := range [][]int{{0, 1, 2, 3, 4}, {4, 5, 6, 7, 8}, {8, 1, 6, 3, 0}} {
+		fs := make([]*Frag, len(sel))
+		for i, j := range sel {
+			fs[i] = frags[j]
+		}
+		out, err := ReconstructIn(GF65536{}, fs)
+		if err != nil {
+			t.Errorf("%v: %v", sel, err)
+			continue
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("%v: data mismatch", sel)
+		}
+	}
+	sf, err := e.FragmentStream(bytes.NewReader(data), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReconstructIn(GF65536{}, sf)
+	if err != nil || !bytes.Equal(out, data) {
+		t.Errorf("FragmentStream: data mismatch (%v)", err)
+	}
+	if _, err := Reconstruct(frags[0:5]); err == nil {
+		// fragments encoded in GF65536 make no sense in Z(Prime); the digest catches it
+		t.Errorf("Reconstruct in Z(Prime): want error")
+	}
+
+	// as Reconstruct does, ReconstructIn ignores nil fragments and outvotes a corrupt one in front
+	for _, fs := range [][]*Frag{nil, {}, {nil, nil}} {
+		if _, err := ReconstructIn(GF65536{}, fs); err != ErrTooFewFragments {
+			t.Errorf("ReconstructIn(%v): want %v got %v", fs, ErrTooFewFragments, err)
+		}
+	}
+	lead := frags[0].Clone()
+	lead.M = 2
+	lead.Len = 7
+	lead.CRC = 0
+	fs := []*Frag{lead, nil, frags[1], nil, frags[2], frags[3], frags[4], frags[5]}
+	if out, err := ReconstructIn(GF65536{}, fs); err != nil || !bytes.Equal(out, data) {
+		t.Errorf("ReconstructIn with nil and corrupt fragments: data mismatch (%v)", err)
+	}
+	wrong := frags[1].Clone()
+	wrong.Enc[0] ^= 1
+	wrong.CRC = wrong.checksum()
+	if _, err := ReconstructIn(GF65536{}, []*Frag{frags[0], wrong, frags[2], frags[3], frags[4]}); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("ReconstructIn with altered fragment: want %v got %v", ErrDigestMismatch, err)
+	}
+	// values off the wire that are not in the field are rejected, not used as table indices
+	for _, c := range []struct {
+		name   string
+		modify func(*Frag)
+	}{
+		{"Enc", func(f *Frag) { f.Enc[0] = 70000 }},
+		{"negative Enc", func(f *Frag) { f.Enc[0] = -1 }},
+		{"A", func(f *Frag) { f.A[0] = 70000 }},
+	} {
+		bad := frags[2].Clone()
+		c.modify(bad)
+		var fe *FragmentError
+		if _, err := ReconstructIn(GF65536{}, []*Frag{frags[0], frags[1], bad, frags[3], frags[4]}); !errors.Is(err, ErrInvalidValue) || !errors.As(err, &fe) || fe.Index != 2 {
+			t.Errorf("ReconstructIn with %s out of range: want fragment 2: %v got %v", c.name, ErrInvalidValue, err)
+		}
+	}
+	if _, err := ReconstructIn(GF65536{}, []*Frag{frags[0], frags[0], frags[2], frags[3], frags[4]}); !errors.Is(err, ErrZeroPivot) {
+		t.Errorf("ReconstructIn with a repeated row: want %v got %v", ErrZeroPivot, err)
+	}
+}
+
+func benchmarkFragmentIn(b *testing.B, ar Arithmetic) {
+	data := make([]byte, 1<<20)
+	rand.Read(data)
+	e := NewEncoder(10, nil)
+	e.Field = ar
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.Fragment(data)
+	}
+}
+
+func BenchmarkFragmentZp(b *testing.B) {
+	benchmarkFragmentIn(b, nil)
+}
+
+func BenchmarkFragmentGF65536(b *testing.B) {
+	benchmarkFragmentIn(b, GF65536{})
+}
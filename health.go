@@ -0,0 +1,54 @@
+package ida
+
+// Health classifies a set of fragments by whether the data can be recovered from it.
+type Health int
+
+const (
+	Critical Health = iota // fewer than m usable fragments: the data cannot be recovered
+	Degraded               // exactly m usable fragments: the data can be recovered, but losing any more would lose it
+	Healthy                // more than m usable fragments
+)
+
+var healthNames = [...]string{
+	Critical: "critical",
+	Degraded: "degraded",
+	Healthy:  "healthy",
+}
+
+func (h Health) String() string {
+	if h < 0 || int(h) >= len(healthNames) {
+		return "Health(?)"
+	}
+	return healthNames[h]
+}
+
+// HealthCheck returns the Health of the set of fragments frags, and the number of usable fragments,
+// those left by [Consistent], provided m of them have linearly independent rows (otherwise there are none).
+// It returns an error only if Consistent cannot decide on the set's parameters (ErrUnstableParameters);
+// an empty set is simply Critical.
+func HealthCheck(frags []*Frag) (Health, int, error) {
+	if len(frags) == 0 {
+		return Critical, 0, nil
+	}
+	good, err := Consistent(frags)
+	switch err {
+	case nil:
+	case ErrNoConsistency:
+		return Critical, 0, nil
+	default:
+		return Critical, 0, err
+	}
+	m := good[0].M
+	rows := make(Matrix, len(good))
+	for i, f := range good {
+		rows[i] = f.A
+	}
+	switch {
+	case rows.Rank() < m:
+		return Critical, 0, nil
+	case len(good) == m:
+		return Degraded, m, nil
+	default:
+		return Healthy, len(good), nil
+	}
+}
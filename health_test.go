@@ -0,0 +1,33 @@
+package ida
+
+import (
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	frags, err := Encode([]byte("how are we doing?"), 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	frags[4].Enc[0] ^= 1 // fails its CRC
+	for _, c := range []struct {
+		set    []*Frag
+		health Health
+		n      int
+	}{
+		{frags[0:4], Healthy, 4},
+		{frags, Healthy, 4},
+		{frags[1:4], Degraded, 3},
+		{frags[2:5], Critical, 0},
+		{[]*Frag{frags[0], frags[1], frags[1]}, Critical, 0},
+		{nil, Critical, 0},
+	} {
+		h, n, err := HealthCheck(c.set)
+		if err != nil || h != c.health || n != c.n {
+			t.Errorf("%d fragments: want %v, %d got %v, %d, %v", len(c.set), c.health, c.n, h, n, err)
+		}
+	}
+	if s := Degraded.String(); s != "degraded" {
+		t.Errorf("Degraded.String: got %q", s)
+	}
+}
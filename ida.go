@@ -9,6 +9,10 @@
 // At least m calls must be made to [Fragment] to obtain enough such fragments to
 // be able to rebuild the data; invariably more fragments are generated to provide
 // the desired level of redundancy.
+// [Encode] does that in one call, returning n fragments with distinct encoding rows.
+// An [Encoder] does the same using a given source of random numbers, for instance to make
+// the fragments reproducible.
+// [SystematicEncode] instead makes m of the n fragments hold the data itself.
 //
 // [Reconstruct] takes an array frags of distinct fragments previously produced by repeated calls to
 // [Fragment](data, m) and returns a tuple data, err.
@@ -19,18 +23,38 @@
 // they represent the same encoding parameters, including the value of m.
 // If it detects an inconsistency, it returns a diagnostic.
 //
+// [FragmentStream] and [ReconstructStream] do the same for data read from an [io.Reader]
+// and written to an [io.Writer], a block at a time.
+// A [Decoder] reconstructs many objects encoded with the same rows, inverting their matrix only once.
+//
 // [Consistent] checks the consistency of a set of fragments, and returns a new subset
 // containing only those fragments the agree with the majority in frags on each parameter.
 //
+// No function of the package retains a reference to a byte slice it is given, or to one it returns:
+// the encoding functions, including Encoder.Append, read the data only during the call,
+// and the fragments they return share no memory with it, or with each other;
+// the reconstruction functions return newly allocated data, except ReconstructInto,
+// which writes only dst[0:n], and does not keep it.
+// So a memory-mapped file can be fragmented in place, and a pooled buffer reused as soon as a call returns.
+// (If ReconstructInto returns an error, dst may have been partly written.)
+// Fragments are another matter: functions that select from a set of fragments,
+// such as Consistent and SelectIndependent, return the same *Frag values, not copies.
+//
 // [Rabin]: https://dl.acm.org/doi/10.1145/62044.62050
 // M Rabin, “Efficient Dispersal of Information for Security,
 // Load Balancing, and Fault Tolerance”, JACM 36(2), April 1989, pp. 335-348.
 // The field Z(65537) used here is that suggested at the top of page 340.
+// An Encoder can instead use another field, such as [GF65536], with [ReconstructIn].
 package ida
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"slices"
 )
 
 var (
@@ -40,183 +64,1227 @@ var (
 	ErrCorruptOutput        = errors.New("corrupt output: impossible value")
 	ErrUnstableParameters   = errors.New("cannot find stable parameter values in this set")
 	ErrNoConsistency        = errors.New("no consistent set found")
+	ErrInvalidM             = errors.New("minimum fragment count m must be at least 1")
+	ErrInvalidN             = errors.New("fragment count n must be at least m")
+	ErrInvalidRow           = errors.New("encoding row value out of range")
+	ErrInvalidValue         = errors.New("encoded value out of range")
+	ErrInvalidLen           = errors.New("negative data length")
+	ErrDataTooLarge         = errors.New("data too large to hold in memory")
+	ErrDigestMismatch       = errors.New("reconstructed data does not match digest")
+	ErrNoDigest             = errors.New("fragments have no digest")
+	ErrMixedSets            = errors.New("fragment from a different set")
+	ErrNoRoom               = errors.New("data too long for the fragment length")
 )
 
+// FragmentError reports a problem with a particular fragment in the set given to a function,
+// such as Reconstruct, so that its source can be identified.
+// The Reason is one of the package's errors, such as ErrInconsistentFragment, which errors.Is will find.
+type FragmentError struct {
+	Index  int // index of the fragment in the set given
+	Reason error
+}
+
+func (e *FragmentError) Error() string {
+	return fmt.Sprintf("fragment %d: %v", e.Index, e.Reason)
+}
+
+func (e *FragmentError) Unwrap() error {
+	return e.Reason
+}
+
+// CorruptOutputError reports where reconstruction produced an impossible data word, one too large for two bytes,
+// which only corrupt fragments can cause: word Word (from 0 to m-1) of column Column of the fragments' Enc values,
+// which would be at byte offset 2*(Column*m+Word) in the data.
+// The data is not returned. errors.Is finds ErrCorruptOutput in it.
+type CorruptOutputError struct {
+	Column int
+	Word   int
+}
+
+func (e *CorruptOutputError) Error() string {
+	return fmt.Sprintf("%v: column %d word %d", ErrCorruptOutput, e.Column, e.Word)
+}
+
+func (e *CorruptOutputError) Unwrap() error {
+	return ErrCorruptOutput
+}
+
 // Frag represents one fragment of a set of fragments that together redundantly represent the original data.
-// The members are exported only to allow any available marshalling scheme to see them (gob, for instance).
+// The members are exported only to allow any available marshalling scheme to see them.
 // The value of all members must be stored and recovered for reconstruction.
+// Frag provides its own compact binary encoding, which gob also uses (see [Frag.GobDecode] for older gob streams).
 type Frag struct {
 
 	// Len is the length in bytes of the original data.
-	Len int
+	// It is 64 bits even on 32-bit machines, so that a streamed object can be larger than a slice;
+	// the functions that return the data in memory give ErrDataTooLarge if it does not fit.
+	Len int64
 
 	// M is the minimum pieces for reconstruction.
 	M int
 
-	// Encoding array row (of an MxM matrix) for this fragment, values in the interval [1, MaxVal]
+	// Encoding array row (of an MxM matrix) for this fragment, values in the interval [1, MaxVal],
+	// or a row of the identity matrix for the systematic fragments made by SystematicEncode.
 	A []Field
 
-	// Encoded data, length ceil(Len/2*M), values in the interval [0, MaxVal].
+	// Encoded data, length ceil(Len/2*M) plus Pad, values in the interval [0, MaxVal].
 	Enc []int
+
+	// Pad is the number of zero values at the end of Enc beyond those that encode the data,
+	// added by FragmentPadded so that fragments of data of different lengths can be the same size.
+	Pad int
+
+	// Index identifies the fragment within its set, counting from 1; zero means it is unknown.
+	// For fragments made by EncodeIndexed or FragmentID, it is the node id giving the encoding row.
+	// It is not needed for reconstruction.
+	Index int
+
+	// SetID is a random value shared by the fragments made by one call of Encode or the like,
+	// so that Consistent and Reconstruct can refuse to combine fragments of different sets,
+	// even of the same data, with the same parameters. Zero means there is none,
+	// as for fragments made one at a time by Fragment, and such fragments are not checked.
+	SetID uint64
+
+	// CRC is a checksum of Len, M, A, Enc, Blocks, SetID and Pad, allowing corruption of the fragment to be detected by Verify.
+	// Zero means there is none.
+	CRC uint32
+
+	// Digest is the SHA-256 hash of the original data, the same for all fragments of a set,
+	// allowing Reconstruct to check its result. It is nil if there is none.
+	Digest []byte
+
+	// Blocks gives the lengths of the blocks of data added by Encoder.Append, in order, summing to Len,
+	// so that ReconstructBlocks can separate them again. It is nil for data encoded in one piece.
+	Blocks []int64
+
+	// MAC is an HMAC-SHA256 of the rest of the fragment under a key shared by its makers and users,
+	// set by FragmentAuth and checked by VerifyAuth. It is nil if there is none.
+	MAC []byte
 }
 
 // Fragment returns a Frag representing the encoded version of data, where
 // at least m fragments are to be required to reconstruct the original data.
+// Data may be empty, giving a fragment with Len 0 and no Enc values,
+// from which Reconstruct returns an empty (but non-nil) slice.
+// M must be at least 1, and Fragment panics otherwise; see [FragmentChecked].
+// When m is 1, each fragment alone is enough to reconstruct the data,
+// so the fragments are simply replicas (scaled by different factors).
 func Fragment(data []byte, m int) *Frag {
-	nb := len(data)
-	nw := (nb + 1) / 2
-	a := randomVec(m)
-	f := make([]int, (nw+m-1)/m)
-	o := 0
+	return NewEncoder(m, nil).Fragment(data)
+}
+
+// FragmentChecked is like [Fragment] but returns an error instead of panicking if m < 1.
+func FragmentChecked(data []byte, m int) (*Frag, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	return Fragment(data, m), nil
+}
+
+// FragmentSecure is like [Fragment] but draws the encoding row from crypto/rand,
+// so that it cannot be predicted. That is slower than the default source,
+// and worthwhile only when the fragments must resist an adversary.
+// It returns an error if m < 1 or the entropy source fails.
+func FragmentSecure(data []byte, m int) (*Frag, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	a, err := secureVec(m)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate encoding row: %w", err)
+	}
+	return fragment(data, a), nil
+}
+
+// FragmentWith is like [Fragment] but uses the given encoding row a instead of a random one,
+// allowing a fragment to be regenerated exactly.
+// The value of m is len(a).
+// It returns an error if a is empty or has an element outside the interval [1, MaxVal],
+// unless a is a row of the identity matrix.
+func FragmentWith(data []byte, a []Field) (*Frag, error) {
+	if len(a) < 1 {
+		return nil, ErrInvalidM
+	}
+	if badrow(a) {
+		return nil, ErrInvalidRow
+	}
+	return fragment(data, slices.Clone(a)), nil
+}
+
+// FragmentPadded is like [Fragment] but pads the fragment's Enc with zeros to targetWords values,
+// as if the data were padded with zero bytes, so that fragments of objects of different lengths
+// can be the same size. Len is the length of data, and reconstruction discards the padding.
+// It returns ErrInvalidM if m < 1, and ErrNoRoom if the data needs more than targetWords values.
+func FragmentPadded(data []byte, m, targetWords int) (*Frag, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	if enclen(int64(len(data)), m) > int64(targetWords) {
+		return nil, ErrNoRoom
+	}
+	f := Fragment(data, m)
+	f.Pad = targetWords - len(f.Enc)
+	f.Enc = append(f.Enc, make([]int, f.Pad)...)
+	f.CRC = f.checksum()
+	return f, nil
+}
+
+// FragmentID is like [Fragment] but uses the encoding row for node id given by [VandermondeRow],
+// so that fragments for any m distinct ids are always enough to reconstruct the data.
+// It returns an error if m < 1 or id is not in the interval [1, MaxVal].
+func FragmentID(data []byte, id Field, m int) (*Frag, error) {
+	a, err := VandermondeRow(id, m)
+	if err != nil {
+		return nil, err
+	}
+	f := fragment(data, a)
+	f.Index = int(id)
+	return f, nil
+}
+
+// fragment returns the Frag encoding data using the encoding row a.
+func fragment(data []byte, a []Field) *Frag {
+	return newFrag(int64(len(data)), a, encode(data, a), digest(data))
+}
+
+// newFrag returns a Frag with the given values, and its CRC set.
+func newFrag(dlen int64, a []Field, enc []int, dg []byte) *Frag {
+	f := &Frag{Len: dlen, M: len(a), A: a, Enc: enc, Digest: dg}
+	f.CRC = f.checksum()
+	return f
+}
+
+// digest returns the Digest of data.
+func digest(data []byte) []byte {
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+// encode returns the Enc values encoding data using the encoding row a.
+func encode(data []byte, a []Field) []int {
+	m := len(a)
+	nw, fl := FragGeometry(len(data), m)
+	f := make([]int, fl)
 	i := 0
-	for _ = range f {
+	for o := range f {
 		c := zero
-		for j := 0; j < m && i < nb; j++ {
-			b := Field(data[i]) << 8
+		for j := 0; j < m && i < nw; j++ {
+			c = c.add(word(data, i).mul(a[j]))
 			i++
-			if i < nb {
-				b |= Field(data[i])
-				i++
-			}
-			c = c.add(b.mul(a[j]))
 		}
 		f[o] = int(c)
-		o++
 	}
-	return &Frag{Len: nb, M: m, A: a, Enc: f}
+	return f
+}
+
+// PackWords returns data packed as field elements, two bytes to a word, big-endian,
+// as they are encoded by [Encoder.Fragment] and the others.
+// If the length of data is odd, the last byte is the high-order byte of the last word,
+// and its low-order byte is zero; the data's length, kept in each Frag,
+// tells [UnpackWords] to discard it.
+func PackWords(data []byte) []Field {
+	w := make([]Field, (len(data)+1)/2)
+	for i := range w {
+		w[i] = word(data, i)
+	}
+	return w
+}
+
+// UnpackWords returns the first dlen bytes of words unpacked as by [PackWords],
+// so UnpackWords(PackWords(data), len(data)) is data.
+// Only the low-order 16 bits of each word are used.
+// If words holds fewer than dlen bytes, the rest of the result is zero.
+func UnpackWords(words []Field, dlen int) []byte {
+	out := make([]byte, dlen)
+	unpack(out, words)
+	return out
+}
+
+// PackWordsWidth returns data packed as field elements, width bytes to a word, big-endian,
+// as PackWords does for a width of 2. Width must be no more than the WordBytes of the field.
+// If the length of data is not a multiple of width, the last word holds the remaining bytes
+// in its high-order bytes, and its low-order bytes are zero.
+// It panics with ErrWordWidth unless width is 1 to 4.
+func PackWordsWidth(data []byte, width int) []Field {
+	if width < 1 || width > 4 {
+		panic(ErrWordWidth)
+	}
+	w := make([]Field, (len(data)+width-1)/width)
+	for i := range w {
+		w[i] = wordWidth(data, i, width)
+	}
+	return w
+}
+
+// UnpackWordsWidth returns the first dlen bytes of words unpacked as by [PackWordsWidth] with the same width,
+// so UnpackWordsWidth(PackWordsWidth(data, width), len(data), width) is data.
+// Only the low-order width bytes of each word are used.
+// If words holds fewer than dlen bytes, the rest of the result is zero.
+// It panics with ErrWordWidth unless width is 1 to 4.
+func UnpackWordsWidth(words []Field, dlen int, width int) []byte {
+	if width < 1 || width > 4 {
+		panic(ErrWordWidth)
+	}
+	out := make([]byte, dlen)
+	unpackWidth(out, words, width)
+	return out
+}
+
+// wordWidth returns the i'th word of data packed width bytes to a word, as by PackWordsWidth.
+func wordWidth(data []byte, i, width int) Field {
+	var b Field
+	for k := 0; k < width; k++ {
+		b <<= 8
+		if o := i*width + k; o < len(data) {
+			b |= Field(data[o])
+		}
+	}
+	return b
+}
+
+// unpackWidth stores as many bytes of words, width bytes to a word, as fit in out,
+// as for UnpackWordsWidth, and returns the number stored.
+func unpackWidth(out []byte, words []Field, width int) int {
+	o := 0
+	for _, b := range words {
+		for k := width - 1; k >= 0 && o < len(out); k-- {
+			out[o] = byte(b >> (8 * k))
+			o++
+		}
+	}
+	return o
+}
+
+// word returns the i'th word of data packed as by PackWords.
+func word(data []byte, i int) Field {
+	b := Field(data[2*i]) << 8
+	if 2*i+1 < len(data) {
+		b |= Field(data[2*i+1])
+	}
+	return b
+}
+
+// unpack stores as many bytes of words as fit in out, as for UnpackWords, and returns the number stored.
+func unpack(out []byte, words []Field) int {
+	o := 0
+	for _, b := range words {
+		if o < len(out) {
+			out[o] = byte(b >> 8)
+			o++
+		}
+		if o < len(out) {
+			out[o] = byte(b)
+			o++
+		}
+	}
+	return o
+}
+
+// FragGeometry returns the number of field words into which data of length dataLen is packed (see [PackWords]),
+// and the number of Enc values in each fragment of it when m fragments are needed for reconstruction,
+// as made by Fragment and the others, but not FragmentPadded. M must be at least 1.
+func FragGeometry(dataLen, m int) (words, fragLen int) {
+	return dataLen/2 + dataLen%2, int(enclen(int64(dataLen), m))
+}
+
+// enclen returns the length of Enc for data of length dlen and minimum fragments m:
+// the data is packed two bytes to a word, and each Enc value encodes m words.
+func enclen(dlen int64, m int) int64 {
+	return enclenWidth(dlen, m, 2)
+}
+
+// enclenWidth is enclen for data packed width bytes to a word, as in a FieldCtx with that WordBytes.
+// Both divisions round up without adding first, so that neither overflows for a Len near math.MaxInt64.
+func enclenWidth(dlen int64, m, width int) int64 {
+	nw := dlen/int64(width) + min(dlen%int64(width), 1)
+	n := nw / int64(m)
+	if nw%int64(m) != 0 {
+		n++
+	}
+	return n
+}
+
+// Encode returns n fragments of data, any m of which are normally enough to reconstruct it.
+// The encoding rows of the fragments are distinct, and their Index values are 1 to n.
+// It returns an error if m < 1 or n < m.
+func Encode(data []byte, m, n int) ([]*Frag, error) {
+	return NewEncoder(m, nil).Encode(data, n)
+}
+
+// EncodeIndexed returns n fragments of data, with Index values 1 to n,
+// and encoding rows given by [VandermondeRow] for those indices,
+// so that any m of them are enough to reconstruct the data,
+// and the row of any fragment can be rederived from its index.
+// It returns an error if m < 1, n < m, or n > MaxVal.
+func EncodeIndexed(data []byte, m, n int) ([]*Frag, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	if n < m || n > int(MaxVal) {
+		return nil, ErrInvalidN
+	}
+	dg := digest(data)
+	frags := make([]*Frag, n)
+	id := newSetID(nil)
+	for i := range frags {
+		a, err := VandermondeRow(Field(i+1), m)
+		if err != nil {
+			return nil, err
+		}
+		frags[i] = newFrag(int64(len(data)), a, encode(data, a), slices.Clone(dg))
+		frags[i].member(i+1, id)
+	}
+	return frags, nil
 }
 
 // Reconstruct returns the data encoded by the given consistent set of fragments.
+// If there are more than m fragments, it uses the first m that are linearly independent (skipping any duplicates),
+// so any surplus fragments available can simply be passed along.
+// If the fragments have a Digest, the data is checked against the one held by most of them
+// (with ties resolved as for Consistent).
 // See [Consistent] for a function that can sort through an arbitrary set of fragments representing the same data
 // and return a consistent set.
 func Reconstruct(frags []*Frag) ([]byte, error) {
-	if len(frags) < 1 || len(frags) < frags[0].M {
-		return nil, ErrTooFewFragments
+	d, err := selectDecoder(frags)
+	if err != nil {
+		return nil, err
 	}
-	m := frags[0].M
-	fraglen := len(frags[0].Enc)
-	dlen := frags[0].Len
+	return d.reconstruct()
+}
 
-	a := NewMatrix(m)
-	for j := range a {
-		a[j] = frags[j].A
-		if len(a[j]) != m {
-			return nil, ErrInconsistentMatrix
+// ReconstructUsed is like [Reconstruct] but also returns the indices in frags
+// of the m fragments actually used to reconstruct the data, in the order used.
+// Their Index values, if set, identify them within the original set.
+func ReconstructUsed(frags []*Frag) ([]byte, []int, error) {
+	d, err := selectDecoder(frags)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := d.reconstruct()
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, d.used, nil
+}
+
+// robustAttempts limits the number of alternative selections tried by ReconstructRobust.
+const robustAttempts = 64
+
+// ReconstructRobust is like [ReconstructUsed], but if the fragments it selects decode to
+// corrupt output or data that does not match their Digest, showing that at least one of them is bad,
+// it tries again with a spare fragment in place of each of those selected in turn,
+// making up to 64 further attempts, and returns the first data that decodes correctly,
+// with the indices in frags of the fragments used; any fragment selected the first time but not the last
+// is suspect. It cannot recover from more than one bad fragment in the first selection,
+// and without a Digest, a bad fragment is noticed only if it produces an impossible value.
+// If no attempt succeeds, it returns the first error.
+func ReconstructRobust(frags []*Frag) ([]byte, []int, error) {
+	d, err := selectDecoder(frags)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := d.reconstruct()
+	if err == nil {
+		return out, d.used, nil
+	}
+	if !errors.Is(err, ErrCorruptOutput) && !errors.Is(err, ErrDigestMismatch) {
+		return nil, nil, err
+	}
+	var spares []int
+	for i, f := range frags {
+		if f != nil && !badcrc(f) && !slices.Contains(d.used, i) {
+			spares = append(spares, i)
 		}
-		if len(frags[j].Enc) != fraglen || frags[j].Len != dlen {
-			return nil, ErrInconsistentFragment
+	}
+	sel := make([]*Frag, len(d.used))
+	tries := 0
+	for k := range d.used {
+		for _, s := range spares {
+			if tries == robustAttempts {
+				return nil, nil, err
+			}
+			tries++
+			idx := slices.Clone(d.used)
+			idx[k] = s
+			for i, j := range idx {
+				sel[i] = frags[j]
+			}
+			d1, err1 := selectDecoder(sel)
+			if err1 != nil {
+				continue
+			}
+			if out, err1 = d1.reconstruct(); err1 == nil {
+				used := make([]int, len(d1.used))
+				for i, j := range d1.used {
+					used[i] = idx[j]
+				}
+				return out, used, nil
+			}
+		}
+	}
+	return nil, nil, err
+}
+
+// ReconstructFrom returns the data encoded by fragments received from ch, which require m for reconstruction,
+// decoding as soon as m independent and consistent fragments have arrived,
+// so that decoding can start while slower fragments are still being fetched.
+// Fragments that are nil, fail their CRC, have a different M, or add nothing to those already received are ignored.
+// Fragments are consistent if they agree in Len, the length of Enc, SetID and Digest, and those that disagree
+// are kept apart, so a wrong fragment arriving first does not prevent reconstruction from the others.
+// Once it has enough, ReconstructFrom discards the rest of the fragments from ch in a separate goroutine,
+// so ch must be closed eventually.
+// It returns ErrTooFewFragments if ch is closed before m consistent fragments arrive.
+func ReconstructFrom(ch <-chan *Frag, m int) ([]byte, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	type key struct {
+		dlen    int64
+		fraglen int
+		id      uint64
+		digest  string
+	}
+	type group struct {
+		b     basis
+		frags []*Frag
+	}
+	groups := make(map[key]*group)
+	for f := range ch {
+		if f == nil || f.M != m || badcrc(f) || f.shape() != nil {
+			continue
+		}
+		k := key{f.Len, len(f.Enc), f.SetID, string(f.Digest)}
+		g := groups[k]
+		if g == nil {
+			g = &group{}
+			groups[k] = g
 		}
+		if !g.b.add(f.A) {
+			continue
+		}
+		g.frags = append(g.frags, f)
+		if len(g.frags) == m {
+			go func() {
+				for range ch {
+				}
+			}()
+			return Reconstruct(g.frags)
+		}
+	}
+	return nil, ErrTooFewFragments
+}
+
+// ReconstructInto is like [Reconstruct] but stores the data in dst instead of allocating a new slice,
+// returning the length of the data.
+// It returns [io.ErrShortBuffer] if dst is too small for the data.
+// On other errors, such as a digest mismatch, dst may have been partly written.
+// It keeps no reference to dst.
+func ReconstructInto(frags []*Frag, dst []byte) (int, error) {
+	d, err := newDecoder(frags)
+	if err != nil {
+		return 0, err
 	}
-	ainv, err := a.Invert()
+	dlen, err := d.size()
 	if err != nil {
-		return nil, fmt.Errorf("invalid decoding matrix: %v", err)
+		return 0, err
 	}
-	out := make([]byte, fraglen*2*m)
-	o := 0
-	for k := range frags[0].Enc {
-		for i := 0; i < m; i++ {
-			row := ainv[i]
-			b := zero
-			for j := 0; j < m; j++ {
-				b = b.add(Field(frags[j].Enc[k]).mul(row[j]))
-			}
-			if (b >> 16) != 0 {
-				return nil, ErrCorruptOutput
+	if len(dst) < dlen {
+		return 0, io.ErrShortBuffer
+	}
+	if err := d.decode(dst[0:dlen]); err != nil {
+		return 0, err
+	}
+	if err := d.check(dst[0:dlen]); err != nil {
+		return 0, err
+	}
+	return dlen, nil
+}
+
+// ReconstructSecure is like [Reconstruct] but for sensitive data: before it returns, it overwrites with zeros
+// the inverse of the encoding matrix and the decoded words, and the data itself if there is an error.
+// That is only a best effort, since the Go runtime is free to have copied the memory elsewhere.
+func ReconstructSecure(frags []*Frag) ([]byte, error) {
+	d, err := newDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	defer d.wipe()
+	dlen, err := d.size()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, dlen)
+	if err := d.decode(out); err != nil {
+		clear(out)
+		return nil, err
+	}
+	if err := d.check(out); err != nil {
+		clear(out)
+		return nil, err
+	}
+	return out, nil
+}
+
+// Repair returns the fragment with encoding row a of the data encoded by frags,
+// without reconstructing the data itself.
+// The result is identical to the one [FragmentWith] would produce from the original data,
+// except that it has the set's SetID, so that it can rejoin the set.
+// Frags need not be consistent, but after discarding inconsistent fragments, at least m must remain.
+func Repair(frags []*Frag, a []Field) (*Frag, error) {
+	frags, err := Consistent(frags)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) != d.m {
+		return nil, ErrInconsistentMatrix
+	}
+	if badrow(a) {
+		return nil, ErrInvalidRow
+	}
+	return d.fragment(a)
+}
+
+// Extend returns extra new fragments of the data encoded by frags, with fresh random encoding rows
+// distinct from those of frags, so as to add redundancy to a set without the data.
+// As for [Repair], the new fragments have the set's M, Len, Digest and SetID,
+// and inconsistent fragments are discarded, after which at least m must remain,
+// or Extend returns ErrTooFewFragments.
+// The new fragments have Index values following the largest in frags.
+func Extend(frags []*Frag, extra int) ([]*Frag, error) {
+	if extra < 0 {
+		return nil, ErrInvalidN
+	}
+	frags, err := Consistent(frags)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]Field, 0, len(frags)+extra)
+	index := 0
+	for _, f := range frags {
+		rows = append(rows, f.A)
+		index = max(index, f.Index)
+	}
+	out := make([]*Frag, extra)
+	for i := range out {
+		a := randomVec(nil, d.m)
+		for slices.ContainsFunc(rows, func(r []Field) bool { return slices.Equal(r, a) }) {
+			a = randomVec(nil, d.m)
+		}
+		rows = append(rows, a)
+		f, err := d.fragment(a)
+		if err != nil {
+			return nil, err
+		}
+		f.Index = index + i + 1
+		out[i] = f
+	}
+	return out, nil
+}
+
+// fragment returns the fragment with encoding row a of the data d decodes,
+// computed from the decoded columns without reconstructing the data,
+// with the Len, Digest, SetID and Blocks of d's fragments.
+func (d *decoder) fragment(a []Field) (*Frag, error) {
+	enc := make([]int, d.fraglen)
+	w := make([]Field, d.m)
+	for k := range enc {
+		if err := d.column(k, w); err != nil {
+			return nil, err
+		}
+		c := zero
+		for j, b := range w {
+			c = c.add(b.mul(a[j]))
+		}
+		enc[k] = int(c)
+	}
+	f := newFrag(d.frags[0].Len, slices.Clone(a), enc, slices.Clone(d.digest))
+	f.SetID = d.setID
+	f.Blocks = slices.Clone(d.frags[0].Blocks)
+	f.Pad = d.frags[0].Pad
+	f.CRC = f.checksum()
+	return f, nil
+}
+
+// Reshard returns a new set of fragments of the data encoded by frags, requiring newM of them for reconstruction.
+// It makes as many fragments as remain in frags after discarding inconsistent ones,
+// and returns an error if that is fewer than newM.
+// The data is reconstructed in memory by [ReconstructSecure], but is not returned,
+// and the buffer is cleared before Reshard returns.
+func Reshard(frags []*Frag, newM int) ([]*Frag, error) {
+	frags, err := Consistent(frags)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ReconstructSecure(frags)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+	return Encode(data, newM, len(frags))
+}
+
+// decoder recovers the data words from a set of m fragments, one column of their Enc values at a time.
+type decoder struct {
+	m       int
+	fraglen int
+	frags   []*Frag // the m fragments, in row order
+	used    []int   // their indices in the set given to newDecoder
+	sys     bool    // frags are the systematic ones
+	a       Matrix  // the encoding matrix
+	ainv    Matrix  // its inverse, if computed, or nil if sys
+	digest  []byte  // majority Digest of the fragments, if any
+	setID   uint64  // majority SetID of the fragments, or zero
+}
+
+// newDecoder returns a decoder for m of a consistent set of fragments, as chosen by selectDecoder,
+// ready to decode columns using the inverse of their encoding matrix.
+func newDecoder(frags []*Frag) (*decoder, error) {
+	d, err := selectDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	if d.sys {
+		return d, nil // no arithmetic needed
+	}
+	ainv, err := d.a.Invert()
+	if err != nil {
+		return nil, fmt.Errorf("invalid decoding matrix: %w", err)
+	}
+	d.ainv = ainv
+	return d, nil
+}
+
+// selectDecoder returns a decoder for m of a consistent set of fragments:
+// its systematic fragments if all are present, or otherwise the first m with
+// linearly independent encoding rows, as chosen by SelectIndependent.
+// The parameters m, Len, the length of Enc, the Digest and the SetID are decided by majority vote,
+// as in [Consistent], not taken from the first fragment, so a corrupt fragment cannot impose them
+// by its position; fragments failing their CRC check have no vote.
+// If there are more than m fragments, those that fail their CRC check or disagree with the vote are skipped.
+func selectDecoder(frags []*Frag) (*decoder, error) {
+	b, err := vote(frags, 2)
+	if err != nil {
+		return nil, err
+	}
+	m := b.m
+	cand := make([]*Frag, len(b.idx))
+	for i, j := range b.idx {
+		cand[i] = frags[j]
+	}
+	sel := sysfrags(cand, m)
+	sys := sel != nil
+	switch {
+	case sys:
+		// use those
+	case len(cand) > m:
+		var err error
+		sel, err = independent(cand)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decoding matrix: %w", err)
+		}
+	default:
+		sel = make([]int, m)
+		for i := range sel {
+			sel[i] = i
+		}
+	}
+	d := &decoder{m: m, fraglen: b.fraglen, frags: make([]*Frag, m), used: make([]int, m), sys: sys}
+	a := NewMatrix(m)
+	for j, i := range sel {
+		f := cand[i]
+		d.frags[j] = f
+		d.used[j] = b.idx[i]
+		a[j] = slices.Clone(f.A) // the matrix must not share the caller's rows
+		if err := b.check(f, b.idx[i]); err != nil {
+			return nil, err
+		}
+	}
+	if b.digest != "" {
+		d.digest = []byte(b.digest)
+	}
+	d.setID = b.setID
+	d.a = a
+	return d, nil
+}
+
+// ballot is the outcome of a vote on the parameters of a set of fragments.
+type ballot struct {
+	m       int
+	dlen    int64
+	fraglen int
+	digest  string
+	setID   uint64
+	width   int   // bytes to a data word
+	idx     []int // candidates for decoding, as indices in the set
+}
+
+// vote decides m, Len, the length of Enc, the Digest and the SetID of frags by majority vote, for selectDecoder,
+// and chooses the candidates for decoding: every fragment present if there are no more than m,
+// and otherwise those that pass their CRC check and agree with the vote.
+// The data is packed width bytes to a word: 2, except in a larger FieldCtx.
+// It returns ErrTooFewFragments if there are fewer than m candidates, which includes a set of none.
+func vote(frags []*Frag, width int) (*ballot, error) {
+	ms := []val[int]{}
+	ds := []val[int64]{}
+	fls := []val[int]{}
+	dgs := []val[string]{}
+	ids := []val[uint64]{}
+	nf := 0 // fragments present
+	for _, f := range frags {
+		if f != nil {
+			nf++
+		}
+	}
+	for pass := 0; pass < 2 && len(ms) == 0; pass++ {
+		for _, f := range frags {
+			if f == nil || pass == 0 && badcrc(f) {
+				continue // if every fragment fails its CRC, give them all a vote
 			}
-			out[o] = byte(b >> 8)
-			o++
-			if o < dlen {
-				out[o] = byte(b)
-				o++
+			ok := f.Verify()
+			ms = addval(ms, f.M, ok)
+			ds = addval(ds, f.Len, ok)
+			fls = addval(fls, len(f.Enc), ok)
+			dgs = addval(dgs, string(f.Digest), ok)
+			if f.SetID != 0 {
+				ids = addval(ids, f.SetID, ok)
 			}
 		}
 	}
-	if dlen < len(out) {
-		out = out[0:dlen]
+	if len(ms) == 0 {
+		return nil, ErrTooFewFragments
+	}
+	m, ok1 := mostly(ms)
+	dlen, ok2 := mostly(ds)
+	fl, ok3 := mostly(fls)
+	dg, ok4 := mostly(dgs)
+	id, ok5 := setvote(ids)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+		return nil, ErrUnstableParameters
+	}
+	b := &ballot{m: m, dlen: dlen, fraglen: fl, digest: dg, setID: id, width: width}
+	b.idx = make([]int, 0, len(frags))
+	for j, f := range frags {
+		if f != nil && (nf <= m || !badcrc(f) && f.M == m && f.Len == dlen && len(f.Enc) == fl && b.sameset(f) && f.shapeWidth(width) == nil) {
+			b.idx = append(b.idx, j)
+		}
+	}
+	if m < 1 || len(b.idx) < m {
+		return nil, ErrTooFewFragments
+	}
+	return b, nil
+}
+
+// sameset returns true if f has the SetID chosen by the vote, or none.
+func (b *ballot) sameset(f *Frag) bool {
+	return f.SetID == 0 || f.SetID == b.setID
+}
+
+// check returns a FragmentError for fragment i of the set, f, if it cannot be decoded with the others chosen by the vote.
+func (b *ballot) check(f *Frag, i int) error {
+	if len(f.A) != b.m {
+		return &FragmentError{i, ErrInconsistentMatrix}
+	}
+	if err := f.shapeWidth(b.width); err != nil {
+		return &FragmentError{i, err}
+	}
+	if len(f.Enc) != b.fraglen || f.Len != b.dlen {
+		return &FragmentError{i, ErrInconsistentFragment}
+	}
+	if !b.sameset(f) {
+		return &FragmentError{i, ErrMixedSets}
+	}
+	return nil
+}
+
+// reconstruct returns the data, decoded a column at a time using the inverse of the encoding matrix,
+// and checks it against the digest.
+// Solving for all the columns at once by Matrix.Solve does as many multiplications (it saves only the O(m³) inversion),
+// and although its row-wise sweeps run faster in BenchmarkReconstruct, it needs the Enc values copied as Fields,
+// several times the size of the data, where this needs no more than the output.
+func (d *decoder) reconstruct() ([]byte, error) {
+	dlen, err := d.size()
+	if err != nil {
+		return nil, err
+	}
+	if !d.sys && d.ainv == nil {
+		ainv, err := d.a.Invert()
+		if err != nil {
+			return nil, fmt.Errorf("invalid decoding matrix: %w", err)
+		}
+		d.ainv = ainv
+	}
+	out := make([]byte, dlen)
+	if err := d.decode(out); err != nil {
+		return nil, err
+	}
+	if err := d.check(out); err != nil {
+		return nil, err
 	}
 	return out, nil
 }
 
+// size returns the length of the data, or ErrDataTooLarge if it cannot be held in a slice.
+func (d *decoder) size() (int, error) {
+	dlen := d.frags[0].Len
+	if dlen < 0 || dlen > math.MaxInt {
+		return 0, ErrDataTooLarge
+	}
+	return int(dlen), nil
+}
+
+// decode stores the data in out, which must have the data's length.
+func (d *decoder) decode(out []byte) error {
+	return d.decodeColumns(out, 0, d.fraglen)
+}
+
+// decodeColumns stores the data from columns [k0, k1) in the corresponding part of out,
+// which must have the data's length.
+// The words are cleared after use, for the sake of ReconstructSecure.
+func (d *decoder) decodeColumns(out []byte, k0, k1 int) error {
+	w := make([]Field, d.m)
+	defer clear(w)
+	o := k0 * 2 * d.m
+	for k := k0; k < k1 && o < len(out); k++ { // columns past the data are padding
+		if err := d.column(k, w); err != nil {
+			return err
+		}
+		o += unpack(out[o:], w)
+	}
+	return nil
+}
+
+// wipe overwrites d's inverse matrix with zeros.
+func (d *decoder) wipe() {
+	for _, row := range d.ainv {
+		clear(row)
+	}
+}
+
+// check returns ErrDigestMismatch if the fragments have a Digest that data does not match.
+func (d *decoder) check(data []byte) error {
+	if d.digest != nil && !bytes.Equal(digest(data), d.digest) {
+		return ErrDigestMismatch
+	}
+	return nil
+}
+
+// column sets w[0:m] to the data words encoded by column k of the fragments.
+// Enc values are sums of products in Z(Prime), so any value in [0, Prime) is legitimate there,
+// including MaxVal (65536). Data words, however, are packed from two bytes (see [PackWords]),
+// so a correctly decoded word is at most 0xFFFF; a larger one can only come from corrupt fragments,
+// and column returns a [*CorruptOutputError].
+func (d *decoder) column(k int, w []Field) error {
+	for i := 0; i < d.m; i++ {
+		var b Field
+		if d.ainv == nil {
+			b = Field(d.frags[i].Enc[k])
+		} else {
+			row := d.ainv[i]
+			b = zero
+			for j := 0; j < d.m; j++ {
+				b = b.add(Field(d.frags[j].Enc[k]).mul(row[j]))
+			}
+		}
+		if (b >> 16) != 0 {
+			return &CorruptOutputError{k, i}
+		}
+		w[i] = b
+	}
+	return nil
+}
+
 // val is one of the parameter values for a set of fragments.
 // In the absence of error, a given parameter value should have the same value in all fragments,
 // and there are typically only a handful of those, so slices are fine for linear search.
-type val struct {
-	v int // value
-	n int // occurrence count
+type val[T comparable] struct {
+	v  T   // value
+	n  int // occurrence count
+	nv int // occurrences in fragments with a CRC that verifies
 }
 
-// addval adds v to list vals, either incrementing the count if it's already
-// listed, or adding it to the list, returning the updated list.
-func addval(vals []val, v int) []val {
+// addval adds v, from a fragment whose CRC does or does not verify, to list vals,
+// either incrementing the counts if it's already listed, or adding it to the list,
+// returning the updated list.
+func addval[T comparable](vals []val[T], v T, verified bool) []val[T] {
+	nv := 0
+	if verified {
+		nv = 1
+	}
 	for l := range vals {
 		if vals[l].v == v {
 			vals[l].n++
+			vals[l].nv += nv
 			return vals
 		}
 	}
-	return append(vals, val{v, 1})
+	return append(vals, val[T]{v, 1, nv})
 }
 
 // mostly returns the most popular value in list vals,
 // returning a tuple (val, ok) where ok is true iff
 // a value was found.
-func mostly(vals []val) (int, bool) {
-	v := val{0, -1}
+// A tie is broken in favour of the value with more verified occurrences,
+// and if that too is tied, no value is found, so the result does not depend on the order of vals.
+func mostly[T comparable](vals []val[T]) (T, bool) {
+	v := val[T]{n: -1}
+	tie := false
 	for _, lv := range vals {
-		if lv.n > v.n {
+		switch {
+		case lv.n > v.n || lv.n == v.n && lv.nv > v.nv:
 			v = lv
+			tie = false
+		case lv.n == v.n && lv.nv == v.nv:
+			tie = true
 		}
 	}
-	if v.n < 0 {
-		return 0, false
+	if v.n < 0 || tie {
+		var z T
+		return z, false
 	}
 	return v.v, true
 }
 
+// setvote returns the SetID held by most fragments, given the votes of those with one,
+// or zero if none has one.
+func setvote(ids []val[uint64]) (uint64, bool) {
+	if len(ids) == 0 {
+		return 0, true
+	}
+	return mostly(ids)
+}
+
 // Consistent returns a consistent set of Frags: all parameters agree with the majority,
-// and obviously bad fragments have been discarded. If no such set can be found,
-// Consistent returns an error.
+// and obviously bad fragments, including those failing their CRC check, have been discarded.
+// Fragments with a SetID must have that of the majority of those with one, so fragments of different
+// encodings are not combined; fragments without one are accepted on their other parameters.
+// Fragments that fail their CRC check are also excluded from the votes on the parameters.
+// Duplicates (fragments with the same A and Enc as an earlier one) are discarded,
+// since they add nothing, and would make the decoding matrix singular.
+// If no such set can be found, Consistent returns an error.
+// A tie in the vote on a parameter goes to the value held by more fragments whose CRC verifies,
+// and otherwise the result would depend on the order of frags, so Consistent returns ErrUnstableParameters.
+// See [ConsistentReport] to find which fragments were discarded, and why.
 func Consistent(frags []*Frag) ([]*Frag, error) {
-	t := make([]*Frag, len(frags))
-	copy(t[0:], frags)
-	frags = t     // leave original untouched
-	ds := []val{} // data size
-	ms := []val{}
-	fls := []val{}
+	return ConsistentMin(frags, 1)
+}
+
+// ConsistentMin is like [Consistent] but returns ErrTooFewFragments if fewer than least fragments survive,
+// typically m, the number needed for reconstruction, so that a set that cannot be reconstructed
+// is rejected at once.
+func ConsistentMin(frags []*Frag, least int) ([]*Frag, error) {
+	good, _, err := ConsistentReport(frags)
+	if err != nil {
+		return nil, err
+	}
+	if len(good) < least {
+		return nil, ErrTooFewFragments
+	}
+	return good, nil
+}
+
+// DropReason says why ConsistentReport dropped a fragment.
+type DropReason int
+
+const (
+	DropNil       DropReason = iota // the fragment is nil
+	DropM                           // M disagrees with the majority, or with the length of A
+	DropLen                         // Len disagrees with the majority
+	DropEncLen                      // the length of Enc disagrees with the majority
+	DropDigest                      // Digest disagrees with the majority
+	DropBadValue                    // the fragment is not Valid, for instance having a value outside the field
+	DropBadCRC                      // the fragment fails its CRC check
+	DropDuplicate                   // the fragment duplicates an earlier one
+	DropSetID                       // SetID disagrees with the majority of those with one
+)
+
+var dropReasons = [...]string{
+	DropNil:       "nil fragment",
+	DropM:         "wrong M",
+	DropLen:       "wrong Len",
+	DropEncLen:    "wrong Enc length",
+	DropDigest:    "wrong Digest",
+	DropBadValue:  "invalid fragment",
+	DropBadCRC:    "CRC mismatch",
+	DropDuplicate: "duplicate",
+	DropSetID:     "wrong SetID",
+}
+
+func (r DropReason) String() string {
+	if r < 0 || int(r) >= len(dropReasons) {
+		return fmt.Sprintf("DropReason(%d)", int(r))
+	}
+	return dropReasons[r]
+}
+
+// DropInfo identifies a fragment dropped by ConsistentReport, and why.
+type DropInfo struct {
+	Index  int // index in the set given to ConsistentReport
+	Reason DropReason
+}
+
+// ConsistentReport is like [Consistent] but also returns the fragments it drops,
+// as their indices in frags, in order, each with the first reason found to drop it.
+// That allows the sources of bad fragments to be identified.
+// The dropped fragments are returned even if there is an error.
+func ConsistentReport(frags []*Frag) (good []*Frag, dropped []DropInfo, err error) {
+	ds := []val[int64]{} // data size
+	ms := []val[int]{}
+	fls := []val[int]{}
+	dgs := []val[string]{}
+	ids := []val[uint64]{}
 	for _, f := range frags {
-		if f != nil {
-			ds = addval(ds, f.Len)
-			ms = addval(ms, f.M)
-			fls = addval(fls, len(f.Enc))
+		if f != nil && !badcrc(f) { // a fragment known to be corrupt has no vote
+			ok := f.Verify()
+			ds = addval(ds, f.Len, ok)
+			ms = addval(ms, f.M, ok)
+			fls = addval(fls, len(f.Enc), ok)
+			dgs = addval(dgs, string(f.Digest), ok)
+			if f.SetID != 0 {
+				ids = addval(ids, f.SetID, ok)
+			}
 		}
 	}
 	dv, ok1 := mostly(ds)
 	mv, ok2 := mostly(ms)
 	flv, ok3 := mostly(fls)
-	if !ok1 || !ok2 || !ok3 {
-		return nil, ErrUnstableParameters
+	dgv, ok4 := mostly(dgs)
+	idv, ok5 := setvote(ids)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+		return nil, nil, ErrUnstableParameters
 	}
-	out := []*Frag{}
-	for _, f := range frags {
-		if f == nil || f.M != mv || f.M != len(f.A) || len(f.Enc) != flv || f.Len != dv || badfrag(f) { // inconsistent: drop it
-			// inconsistent, drop it
+	good = []*Frag{}
+	for i, f := range frags {
+		r := DropReason(-1)
+		switch {
+		case f == nil:
+			r = DropNil
+		case f.M != mv || f.M != len(f.A):
+			r = DropM
+		case f.Len != dv:
+			r = DropLen
+		case len(f.Enc) != flv:
+			r = DropEncLen
+		case string(f.Digest) != dgv:
+			r = DropDigest
+		case f.SetID != 0 && f.SetID != idv:
+			r = DropSetID
+		case f.Valid() != nil:
+			r = DropBadValue
+		case badcrc(f):
+			r = DropBadCRC
+		case slices.ContainsFunc(good, f.same):
+			r = DropDuplicate
+		}
+		if r >= 0 {
+			dropped = append(dropped, DropInfo{i, r})
 			continue
 		}
-		out = append(out, f) // survivor to output list
+		good = append(good, f) // survivor to output list
 	}
-	if len(out) == 0 {
-		return nil, ErrNoConsistency
+	if len(good) == 0 {
+		return nil, dropped, ErrNoConsistency
 	}
-	return out, nil
+	return good, dropped, nil
 }
 
-// badfrag looks for implausible element values and returns true if it finds them.
-func badfrag(f *Frag) bool {
-	for _, v := range f.A {
+// Clone returns a deep copy of f, sharing no storage with it.
+func (f *Frag) Clone() *Frag {
+	g := *f
+	g.A = slices.Clone(f.A)
+	g.Enc = slices.Clone(f.Enc)
+	g.Digest = slices.Clone(f.Digest)
+	g.Blocks = slices.Clone(f.Blocks)
+	g.MAC = slices.Clone(f.MAC)
+	return &g
+}
+
+// Equal returns true if f and g encode the same data in the same way:
+// they have the same Len and M, and the same values in A and Enc.
+// Index, CRC, Digest and Blocks are not compared.
+func (f *Frag) Equal(g *Frag) bool {
+	return f.Len == g.Len && f.M == g.M && f.same(g)
+}
+
+// same returns true if f and g have the same encoding row and encoded values.
+func (f *Frag) same(g *Frag) bool {
+	return slices.Equal(f.A, g.A) && slices.Equal(f.Enc, g.Enc)
+}
+
+// badrow returns true if encoding row a has an element outside the interval [1, MaxVal],
+// unless it is a row of the identity matrix, as used for systematic fragments.
+func badrow(a []Field) bool {
+	if sysrow(a) >= 0 {
+		return false
+	}
+	for _, v := range a {
 		if v <= 0 || v >= Prime {
 			return true
 		}
 	}
+	return false
+}
+
+// Valid returns nil if f is well-formed, and otherwise an error saying why not:
+// ErrInvalidM if M < 1, ErrInconsistentMatrix if A does not have M elements,
+// ErrInvalidLen if Len is negative, ErrInconsistentFragment if Enc is not the right length for Len, M and Pad
+// (or Blocks do not add up to Len, or a fragment with Blocks is padded), ErrInvalidRow if A has a value out of range,
+// and ErrInvalidValue if Enc does, or its padding is not zero.
+// It does not check the CRC; see [Frag.Verify].
+func (f *Frag) Valid() error {
+	if err := f.shape(); err != nil {
+		return err
+	}
+	if badrow(f.A) {
+		return ErrInvalidRow
+	}
+	for _, v := range f.Enc {
+		if v < 0 || v >= Prime {
+			return ErrInvalidValue
+		}
+	}
+	for _, v := range f.Enc[len(f.Enc)-f.Pad:] {
+		if v != 0 {
+			return ErrInvalidValue
+		}
+	}
+	return nil
+}
+
+// shape does the checks of Valid that do not look at the values in A and Enc.
+func (f *Frag) shape() error {
+	return f.shapeWidth(2)
+}
+
+// shapeWidth is shape for a fragment whose data is packed width bytes to a word.
+func (f *Frag) shapeWidth(width int) error {
+	switch {
+	case f.M < 1:
+		return ErrInvalidM
+	case len(f.A) != f.M:
+		return ErrInconsistentMatrix
+	case f.Len < 0:
+		return ErrInvalidLen
+	case f.Pad < 0 || f.Pad > len(f.Enc) || int64(len(f.Enc))-enclenWidth(f.Len, f.M, width) != int64(f.Pad):
+		return ErrInconsistentFragment
+	case f.Blocks != nil && (f.Pad != 0 || badblocks(f.Blocks, f.Len)):
+		return ErrInconsistentFragment
+	}
+	return nil
+}
+
+// badfrag looks for implausible element values and returns true if it finds them.
+func badfrag(f *Frag) bool {
+	if badrow(f.A) {
+		return true
+	}
 	for _, v := range f.Enc {
 		if v < 0 || v >= Prime {
 			return true
@@ -3,9 +3,15 @@ package ida
 // Copyright © 2024 charles.forsyth@gmail.com
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"reflect"
+	"slices"
 	"testing"
 )
 
@@ -71,3 +77,1418 @@ func TestIDA(t *testing.T) {
 		}
 	}
 }
+
+func TestEncode(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	frags, err := Encode(data, 4, 9)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if len(frags) != 9 {
+		t.Fatalf("Encode: want 9 fragments, got %d", len(frags))
+	}
+	for i := range frags {
+		for j := 0; j < i; j++ {
+			if slices.Equal(frags[i].A, frags[j].A) {
+				t.Errorf("Encode: rows %d and %d are the same", i, j)
+			}
+		}
+	}
+	zot, err := Reconstruct(frags[5:])
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+	for _, p := range []struct{ m, n int }{{0, 3}, {-1, 3}, {3, 2}} {
+		if _, err := Encode(data, p.m, p.n); err == nil {
+			t.Errorf("Encode(data, %d, %d): want error", p.m, p.n)
+		}
+	}
+}
+
+func TestFragmentSecure(t *testing.T) {
+	data := []byte("secret sharing, after a fashion")
+	var frags []*Frag
+	for i := 0; i < 6; i++ {
+		f, err := FragmentSecure(data, 4)
+		if err != nil {
+			t.Fatalf("FragmentSecure: %v", err)
+		}
+		if badfrag(f) {
+			t.Errorf("FragmentSecure: bad fragment %#v", f)
+		}
+		frags = append(frags, f)
+	}
+	zot, err := Reconstruct(frags[1:])
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+	if _, err := FragmentSecure(data, 0); err == nil {
+		t.Errorf("FragmentSecure(data, 0): want error")
+	}
+}
+
+func TestFragmentWith(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5}
+	// words 0x0102, 0x0304, 0x0500; columns 0x0102*1+0x0304*2, 0x0500*1
+	f, err := FragmentWith(data, []Field{1, 2})
+	if err != nil {
+		t.Fatalf("FragmentWith: %v", err)
+	}
+	if want := []int{1802, 1280}; !slices.Equal(f.Enc, want) {
+		t.Errorf("FragmentWith: want Enc %v got %v", want, f.Enc)
+	}
+	g, err := FragmentWith(data, []Field{3, MaxVal})
+	if err != nil {
+		t.Fatalf("FragmentWith: %v", err)
+	}
+	zot, err := Reconstruct([]*Frag{f, g})
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %v got %v", data, zot)
+	}
+	for _, a := range [][]Field{nil, {2, 0}, {MaxVal + 1}} {
+		if _, err := FragmentWith(data, a); err == nil {
+			t.Errorf("FragmentWith(data, %v): want error", a)
+		}
+	}
+}
+
+func TestFragGeometry(t *testing.T) {
+	for _, c := range []struct {
+		dlen, m, words, fraglen int
+	}{
+		{0, 1, 0, 0},
+		{0, 5, 0, 0},
+		{1, 1, 1, 1},
+		{1, 3, 1, 1},
+		{2, 1, 1, 1},
+		{3, 1, 2, 2},
+		{5, 3, 3, 1},
+		{6, 3, 3, 1},
+		{7, 3, 4, 2},
+		{12, 3, 6, 2},
+		{13, 3, 7, 3},
+		{100, 7, 50, 8},
+		{1 << 20, 16, 1 << 19, 1 << 15},
+		{1<<20 + 1, 16, 1<<19 + 1, 1<<15 + 1},
+	} {
+		words, fraglen := FragGeometry(c.dlen, c.m)
+		if words != c.words || fraglen != c.fraglen {
+			t.Errorf("FragGeometry(%d, %d): want %d, %d got %d, %d", c.dlen, c.m, c.words, c.fraglen, words, fraglen)
+		}
+	}
+	// rounding up does not overflow
+	if n := enclen(1<<63-1, 1); n != 1<<62 {
+		t.Errorf("enclen(MaxInt64, 1): want %d got %d", int64(1<<62), n)
+	}
+	if n := enclen(1<<63-1, 3); n != (1<<62)/3+1 {
+		t.Errorf("enclen(MaxInt64, 3): want %d got %d", int64((1<<62)/3+1), n)
+	}
+	// and it agrees with what is made
+	rnd := rand.New(rand.NewSource(1))
+	for dlen := 0; dlen < 40; dlen++ {
+		data := make([]byte, dlen)
+		for _, m := range []int{1, 2, 3, 4, 7} {
+			words, fraglen := FragGeometry(dlen, m)
+			if f := NewEncoder(m, rand.NewSource(rnd.Int63())).Fragment(data); len(f.Enc) != fraglen {
+				t.Errorf("Fragment(%d, %d): want %d Enc values got %d", dlen, m, fraglen, len(f.Enc))
+			}
+			if w := PackWords(data); len(w) != words {
+				t.Errorf("PackWords(%d): want %d words got %d", dlen, words, len(w))
+			}
+		}
+	}
+}
+
+func TestFragmentPadded(t *testing.T) {
+	const m, words = 3, 10
+	for _, n := range []int{0, 1, 5, 6, 7, 59, 60} {
+		data := []byte("a slot of fixed size, whatever the length of the object it holds"[0:n])
+		var frags []*Frag
+		for i := 0; i < m+1; i++ {
+			f, err := FragmentPadded(data, m, words)
+			if err != nil {
+				t.Fatalf("FragmentPadded(%d): %v", n, err)
+			}
+			_, fraglen := FragGeometry(n, m)
+			if len(f.Enc) != words || f.Pad != words-fraglen || f.Len != int64(n) || f.Valid() != nil || !f.Verify() {
+				t.Errorf("FragmentPadded(%d): %d words, Pad %d, Len %d, %v", n, len(f.Enc), f.Pad, f.Len, f.Valid())
+			}
+			frags = append(frags, f)
+		}
+		zot, err := Reconstruct(frags[1:])
+		if err != nil || !bytes.Equal(zot, data) {
+			t.Errorf("Reconstruct(%d): want %q got %q, %v", n, data, zot, err)
+		}
+		zot, err = ReconstructParallel(frags[0:m], 4)
+		if err != nil || !bytes.Equal(zot, data) {
+			t.Errorf("ReconstructParallel(%d): want %q got %q, %v", n, data, zot, err)
+		}
+		dst := make([]byte, n)
+		if k, err := ReconstructInto(frags[0:m], dst); err != nil || !bytes.Equal(dst[0:k], data) {
+			t.Errorf("ReconstructInto(%d): want %q got %q, %v", n, data, dst[0:k], err)
+		}
+		r, err := Repair(frags[1:], frags[0].A)
+		if err != nil || !slices.Equal(r.Enc, frags[0].Enc) || r.Pad != frags[0].Pad || r.Valid() != nil {
+			t.Errorf("Repair(%d): want %v got %v, %v", n, frags[0].Enc, r, err)
+		}
+		for _, codec := range []struct {
+			name string
+			enc  func(*Frag) ([]byte, error)
+			dec  func(*Frag, []byte) error
+		}{
+			{"binary", (*Frag).MarshalBinary, (*Frag).UnmarshalBinary},
+			{"JSON", (*Frag).MarshalJSON, (*Frag).UnmarshalJSON},
+		} {
+			buf, err := codec.enc(frags[0])
+			if err != nil {
+				t.Fatalf("%s: %v", codec.name, err)
+			}
+			var g Frag
+			if err := codec.dec(&g, buf); err != nil || !g.Equal(frags[0]) || g.Pad != frags[0].Pad || !g.Verify() {
+				t.Errorf("%s round trip of padded fragment (%d): %v", codec.name, n, err)
+			}
+		}
+	}
+	// padding must be declared, and be zero
+	f, _ := FragmentPadded([]byte("declared"), m, words)
+	if buf, err := f.MarshalBinary(); err != nil || len(buf) != f.Size() {
+		t.Errorf("MarshalBinary of padded fragment: Size %d, %v", f.Size(), err)
+	}
+	undeclared := f.Clone()
+	undeclared.Pad = 0
+	buf, err := undeclared.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var g Frag
+	if err := g.UnmarshalBinary(buf); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("undeclared padding: want %v got %v", ErrBadEncoding, err)
+	}
+	junk := f.Clone()
+	junk.Enc[words-1] = 1
+	buf, _ = junk.MarshalBinary()
+	if err := g.UnmarshalBinary(buf); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("non-zero padding: want %v got %v", ErrInvalidValue, err)
+	}
+	if _, err := FragmentPadded(make([]byte, 61), m, words); err != ErrNoRoom {
+		t.Errorf("FragmentPadded too long: want %v got %v", ErrNoRoom, err)
+	}
+	if _, err := FragmentPadded(nil, 0, words); err != ErrInvalidM {
+		t.Errorf("FragmentPadded m=0: want %v got %v", ErrInvalidM, err)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	data := []byte("a fragment lost is a fragment regained")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	lost := frags[4]
+	f, err := Repair(append(frags[0:4:4], frags[5]), lost.A)
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	if f.Len != lost.Len || f.M != lost.M || !slices.Equal(f.A, lost.A) || !slices.Equal(f.Enc, lost.Enc) {
+		t.Errorf("Repair: want %#v got %#v", lost, f)
+	}
+	if _, err := Repair(frags[0:2], lost.A); err != ErrTooFewFragments {
+		t.Errorf("Repair with 2 fragments: want %v got %v", ErrTooFewFragments, err)
+	}
+}
+
+func TestExtend(t *testing.T) {
+	data := []byte("more redundancy, after the fact")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	more, err := Extend(frags[1:4], 4)
+	if err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	if len(more) != 4 {
+		t.Fatalf("Extend: want 4 fragments got %d", len(more))
+	}
+	all := append(slices.Clone(frags), more...)
+	for i, f := range more {
+		if f.M != 3 || f.Len != int64(len(data)) || f.SetID != frags[0].SetID || !bytes.Equal(f.Digest, frags[0].Digest) || !f.Verify() {
+			t.Errorf("Extend: fragment %d does not match the set: %v", i, f)
+		}
+		if f.Index != 5+i {
+			t.Errorf("Extend: fragment %d: want Index %d got %d", i, 5+i, f.Index)
+		}
+		for _, f1 := range all[0 : 5+i] {
+			if slices.Equal(f.A, f1.A) {
+				t.Errorf("Extend: fragment %d repeats the row of fragment %d", i, f1.Index)
+			}
+		}
+	}
+	// the new fragments alone, or mixed with the old, reconstruct the data
+	for _, set := range [][]*Frag{more, {more[3], frags[0], more[1]}, all} {
+		if zot, err := Reconstruct(set); err != nil || !bytes.Equal(zot, data) {
+			t.Errorf("Reconstruct: want %q got %q, %v", data, zot, err)
+		}
+	}
+	if _, err := Extend(frags[0:2], 1); err != ErrTooFewFragments {
+		t.Errorf("Extend with 2 fragments: want %v got %v", ErrTooFewFragments, err)
+	}
+	if _, err := Extend(frags, -1); err != ErrInvalidN {
+		t.Errorf("Extend(-1): want %v got %v", ErrInvalidN, err)
+	}
+}
+
+func TestReshard(t *testing.T) {
+	data := []byte("changing the reliability parameters")
+	frags, err := Encode(data, 3, 8)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, newM := range []int{3, 1, 5, 8, 100} {
+		nfrags, err := Reshard(frags, newM)
+		if newM > len(frags) {
+			if err == nil {
+				t.Errorf("Reshard(%d): want error", newM)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Reshard(%d): %v", newM, err)
+			continue
+		}
+		if len(nfrags) != len(frags) || nfrags[0].M != newM {
+			t.Errorf("Reshard(%d): got %d fragments with M %d", newM, len(nfrags), nfrags[0].M)
+		}
+		zot, err := Reconstruct(nfrags[len(nfrags)-newM:])
+		if err != nil {
+			t.Errorf("Reshard(%d): Reconstruct: %v", newM, err)
+			continue
+		}
+		if !bytes.Equal(zot, data) {
+			t.Errorf("Reshard(%d): want %q got %q", newM, data, zot)
+		}
+	}
+	// more than the encoded word count
+	frags, err = Encode(data[0:3], 2, 4)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	nfrags, err := Reshard(frags, 4)
+	if err != nil {
+		t.Fatalf("Reshard: %v", err)
+	}
+	if zot, err := Reconstruct(nfrags); err != nil || !bytes.Equal(zot, data[0:3]) {
+		t.Errorf("Reshard beyond word count: want %q got %q, %v", data[0:3], zot, err)
+	}
+}
+
+func TestReconstructInto(t *testing.T) {
+	data := []byte("reconstructed into a buffer the caller owns")
+	frags, err := Encode(data, 5, 7)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	dst := make([]byte, 100)
+	n, err := ReconstructInto(frags[1:6], dst)
+	if err != nil {
+		t.Fatalf("ReconstructInto: %v", err)
+	}
+	if !bytes.Equal(dst[0:n], data) {
+		t.Errorf("ReconstructInto: want %q got %q", data, dst[0:n])
+	}
+	if _, err := ReconstructInto(frags, dst[0:len(data)-1]); err != io.ErrShortBuffer {
+		t.Errorf("ReconstructInto short buffer: want %v got %v", io.ErrShortBuffer, err)
+	}
+}
+
+func TestReconstructSecure(t *testing.T) {
+	data := []byte("nobody else should see this")
+	frags, err := Encode(data, 4, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	out, err := ReconstructSecure(frags[2:6])
+	if err != nil || !bytes.Equal(out, data) {
+		t.Fatalf("ReconstructSecure: want %q got %q, %v", data, out, err)
+	}
+	d, err := newDecoder(frags[0:4])
+	if err != nil {
+		t.Fatalf("newDecoder: %v", err)
+	}
+	d.wipe()
+	for i, row := range d.ainv {
+		for j, v := range row {
+			if v != 0 {
+				t.Fatalf("wipe: ainv[%d][%d] = %d", i, j, v)
+			}
+		}
+	}
+	frags[0].Digest[0] ^= 1
+	frags[1].Digest[0] ^= 1
+	frags[2].Digest[0] ^= 1
+	if out, err := ReconstructSecure(frags[0:4]); err != ErrDigestMismatch || out != nil {
+		t.Errorf("ReconstructSecure bad digest: want %v got %v", ErrDigestMismatch, err)
+	}
+}
+
+func TestEncodeIndexed(t *testing.T) {
+	data := []byte("fragments that know who they are")
+	frags, err := EncodeIndexed(data, 3, 6)
+	if err != nil {
+		t.Fatalf("EncodeIndexed: %v", err)
+	}
+	for i, f := range frags {
+		if f.Index != i+1 {
+			t.Errorf("fragment %d: want Index %d got %d", i, i+1, f.Index)
+		}
+		a, _ := VandermondeRow(Field(f.Index), 3)
+		if !slices.Equal(a, f.A) {
+			t.Errorf("fragment %d: row not derived from Index", i)
+		}
+	}
+	frags[4].Index = 1000 // an odd Index doesn't matter to Consistent or Reconstruct
+	set, err := Consistent([]*Frag{frags[5], frags[4], frags[1]})
+	if err != nil || len(set) != 3 {
+		t.Fatalf("Consistent: got %d fragments, %v", len(set), err)
+	}
+	zot, err := Reconstruct(set)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+}
+
+func TestCRC(t *testing.T) {
+	data := []byte("a single flipped bit should not go unnoticed")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for i, f := range frags {
+		if !f.Verify() {
+			t.Errorf("fragment %d: Verify failed", i)
+		}
+	}
+	frags[0].Enc[1] ^= 1
+	if frags[0].Verify() {
+		t.Errorf("corrupt fragment: Verify succeeded")
+	}
+	set, err := Consistent(frags)
+	if err != nil {
+		t.Fatalf("Consistent: %v", err)
+	}
+	if len(set) != 4 || set[0] != frags[1] {
+		t.Errorf("Consistent: corrupt fragment not dropped")
+	}
+	zot, err := Reconstruct(frags)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+	frags[1].CRC = 0 // no CRC: not checked, but not verified either
+	if frags[1].Verify() {
+		t.Errorf("fragment without CRC: Verify succeeded")
+	}
+	if set, _ := Consistent(frags); len(set) != 4 {
+		t.Errorf("Consistent: fragment without CRC dropped")
+	}
+}
+
+func TestConsistentMin(t *testing.T) {
+	data := []byte("enough survivors, or nothing")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	set := []*Frag{frags[0], nil, frags[2], frags[0]}
+	if good, err := Consistent(set); err != nil || len(good) != 2 {
+		t.Errorf("Consistent: want 2 fragments got %d, %v", len(good), err)
+	}
+	if good, err := ConsistentMin(set, 3); err != ErrTooFewFragments || good != nil {
+		t.Errorf("ConsistentMin(3): want nil, %v got %d fragments, %v", ErrTooFewFragments, len(good), err)
+	}
+	if good, err := ConsistentMin(append(set, frags[4]), 3); err != nil || len(good) != 3 {
+		t.Errorf("ConsistentMin(3) with 3: want 3 fragments got %d, %v", len(good), err)
+	}
+	if _, err := ConsistentMin([]*Frag{nil}, 3); err != ErrUnstableParameters {
+		t.Errorf("ConsistentMin of nil: want %v got %v", ErrUnstableParameters, err)
+	}
+}
+
+func TestConsistentReport(t *testing.T) {
+	data := []byte("which of you is lying?")
+	frags, err := Encode(data, 3, 9)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	frags[1] = nil
+	frags[2].M = 4
+	frags[3].Len++
+	frags[4].Enc = frags[4].Enc[1:]
+	frags[5].Digest = []byte("nonsense")
+	frags[6].Enc[0] = Prime
+	frags[7].Enc[0] ^= 1
+	good, dropped, err := ConsistentReport(frags)
+	if err != nil {
+		t.Fatalf("ConsistentReport: %v", err)
+	}
+	if len(good) != 2 || good[0] != frags[0] || good[1] != frags[8] {
+		t.Errorf("ConsistentReport: wrong fragments kept")
+	}
+	want := []DropInfo{{1, DropNil}, {2, DropM}, {3, DropLen}, {4, DropEncLen}, {5, DropDigest}, {6, DropBadValue}, {7, DropBadCRC}}
+	if !slices.Equal(dropped, want) {
+		t.Errorf("ConsistentReport: want %v got %v", want, dropped)
+	}
+	if s := DropBadCRC.String(); s != "CRC mismatch" {
+		t.Errorf("DropBadCRC.String: got %q", s)
+	}
+}
+
+func TestConsistentTie(t *testing.T) {
+	a, err := Encode([]byte("one version of events"), 2, 2)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	b, err := Encode([]byte("another version of events"), 2, 2)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, set := range [][]*Frag{{a[0], a[1], b[0], b[1]}, {b[1], a[0], b[0], a[1]}} {
+		if _, err := Consistent(set); err != ErrUnstableParameters {
+			t.Errorf("tie: want %v got %v", ErrUnstableParameters, err)
+		}
+	}
+	b[0].CRC = 0 // b's fragments no longer all verify, so a wins either way
+	for _, set := range [][]*Frag{{a[0], a[1], b[0], b[1]}, {b[1], b[0], a[1], a[0]}} {
+		good, err := Consistent(set)
+		if err != nil {
+			t.Errorf("broken tie: %v", err)
+			continue
+		}
+		if len(good) != 2 || good[0].Len != a[0].Len || good[1].Len != a[0].Len {
+			t.Errorf("broken tie: wrong fragments chosen")
+		}
+	}
+}
+
+func TestConsistentCRCVote(t *testing.T) {
+	data := []byte("the corrupt shall not outvote the sound")
+	frags, err := Encode(data, 2, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, f := range frags[2:] {
+		f.Len++ // a majority, but their CRCs no longer match
+	}
+	good, err := Consistent(frags)
+	if err != nil {
+		t.Fatalf("Consistent: %v", err)
+	}
+	if len(good) != 2 || good[0] != frags[0] || good[1] != frags[1] {
+		t.Errorf("Consistent: corrupt fragments won the vote")
+	}
+	for _, f := range frags {
+		f.CRC = 0 // without CRCs, the majority wins
+	}
+	good, err = Consistent(frags)
+	if err != nil || len(good) != 3 || good[0].Len != int64(len(data))+1 {
+		t.Errorf("Consistent without CRC: want 3 fragments of the altered Len, got %d, %v", len(good), err)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	data := []byte("fetched twice by different paths")
+	frags, err := Encode(data, 3, 4)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	dup := *frags[1]
+	set := []*Frag{frags[1], &dup, frags[1], frags[2], frags[3]}
+	good, dropped, err := ConsistentReport(set)
+	if err != nil {
+		t.Fatalf("ConsistentReport: %v", err)
+	}
+	want := []DropInfo{{1, DropDuplicate}, {2, DropDuplicate}}
+	if len(good) != 3 || !slices.Equal(dropped, want) {
+		t.Errorf("ConsistentReport: want %v dropped got %v", want, dropped)
+	}
+	zot, err := Reconstruct(set)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+}
+
+func TestCloneEqual(t *testing.T) {
+	frags, err := Encode([]byte("copy me"), 2, 2)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	f := frags[0]
+	g := f.Clone()
+	if !reflect.DeepEqual(f, g) || !f.Equal(g) {
+		t.Fatalf("Clone: want %#v got %#v", f, g)
+	}
+	g.A[0]++
+	g.Enc[0]++
+	g.Digest[0]++
+	if f.Equal(g) || reflect.DeepEqual(f, g) {
+		t.Errorf("Clone shares storage with the original")
+	}
+	if !f.Equal(frags[0]) || f.Equal(frags[1]) {
+		t.Errorf("Equal: wrong result")
+	}
+	h := f.Clone()
+	h.Index = 99
+	h.CRC = 0
+	if !f.Equal(h) {
+		t.Errorf("Equal: Index or CRC compared")
+	}
+	h.Len++
+	if f.Equal(h) {
+		t.Errorf("Equal: Len not compared")
+	}
+}
+
+func TestValid(t *testing.T) {
+	frags, err := Encode([]byte("well formed"), 3, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if err := frags[0].Valid(); err != nil {
+		t.Fatalf("Valid: %v", err)
+	}
+	for _, c := range []struct {
+		what  string
+		alter func(f *Frag)
+		want  error
+	}{
+		{"M", func(f *Frag) { f.M = 0; f.A = nil }, ErrInvalidM},
+		{"A", func(f *Frag) { f.A = f.A[1:] }, ErrInconsistentMatrix},
+		{"Len", func(f *Frag) { f.Len = -1 }, ErrInvalidLen},
+		{"Enc length", func(f *Frag) { f.Enc = append(f.Enc, 0) }, ErrInconsistentFragment},
+		{"Pad", func(f *Frag) { f.Pad = 1 }, ErrInconsistentFragment},
+		{"negative Pad", func(f *Frag) { f.Pad = -1; f.Enc = f.Enc[1:] }, ErrInconsistentFragment},
+		{"padded Blocks", func(f *Frag) { f.Blocks = []int64{f.Len}; f.Enc = append(f.Enc, 0); f.Pad = 1 }, ErrInconsistentFragment},
+		{"padding value", func(f *Frag) { f.Enc = append(f.Enc, 1); f.Pad = 1 }, ErrInvalidValue},
+		{"Len and Enc", func(f *Frag) { f.Len += 6 }, ErrInconsistentFragment},
+		{"huge Len", func(f *Frag) { f.Len = 1<<63 - 1; f.Pad = 1 }, ErrInconsistentFragment},
+		{"Pad beyond Enc", func(f *Frag) { f.Pad = len(f.Enc) + 1 }, ErrInconsistentFragment},
+		{"Blocks", func(f *Frag) { f.Blocks = []int64{1} }, ErrInconsistentFragment},
+		{"A value", func(f *Frag) { f.A[1] = 0 }, ErrInvalidRow},
+		{"Enc value", func(f *Frag) { f.Enc[0] = Prime }, ErrInvalidValue},
+	} {
+		f := frags[0].Clone()
+		c.alter(f)
+		if err := f.Valid(); err != c.want {
+			t.Errorf("%s: want %v got %v", c.what, c.want, err)
+		}
+	}
+}
+
+func TestFragmentError(t *testing.T) {
+	frags, err := Encode([]byte("which one is wrong?"), 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, f := range frags {
+		f.CRC = 0
+	}
+	frags[3].Enc = frags[3].Enc[1:]
+	if _, err := Reconstruct(frags[1:5]); err != nil {
+		t.Errorf("Reconstruct with a surplus: want it skipped, got %v", err)
+	}
+	_, err = Reconstruct(frags[1:4])
+	var fe *FragmentError
+	if !errors.As(err, &fe) || fe.Index != 2 || !errors.Is(err, ErrInconsistentFragment) {
+		t.Errorf("Reconstruct: want fragment 2 %v, got %v", ErrInconsistentFragment, err)
+	}
+	frags[3].Enc = frags[4].Enc
+	frags[3].A = frags[3].A[1:]
+	_, err = Reconstruct(frags[2:5])
+	if !errors.As(err, &fe) || fe.Index != 1 || !errors.Is(err, ErrInconsistentMatrix) {
+		t.Errorf("Reconstruct: want fragment 1 %v, got %v", ErrInconsistentMatrix, err)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	data := []byte("end to end, the bytes must match")
+	other := []byte("end to end, the bytes must MATCH")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if want := sha256.Sum256(data); !bytes.Equal(frags[0].Digest, want[:]) {
+		t.Errorf("Encode: Digest not set")
+	}
+	// a wrong but otherwise consistent quorum
+	wrong, err := Encode(other, 3, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, f := range wrong {
+		f.Digest = frags[0].Digest
+	}
+	if _, err := Reconstruct(wrong); err != ErrDigestMismatch {
+		t.Errorf("Reconstruct: want %v got %v", ErrDigestMismatch, err)
+	}
+	// Consistent votes on the digest
+	wrong, _ = Encode(other, 3, 2)
+	set, err := Consistent(append(wrong, frags...))
+	if err != nil {
+		t.Fatalf("Consistent: %v", err)
+	}
+	if len(set) != len(frags) {
+		t.Errorf("Consistent: want %d fragments got %d", len(frags), len(set))
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, data := range [][]byte{nil, {}} {
+		var frags []*Frag
+		for i := 0; i < 3; i++ {
+			f := Fragment(data, 3)
+			if f.Len != 0 || len(f.Enc) != 0 || len(f.A) != 3 || badfrag(f) {
+				t.Errorf("Fragment(%#v, 3): bad fragment %#v", data, f)
+			}
+			frags = append(frags, f)
+		}
+		zot, err := Reconstruct(frags)
+		if err != nil {
+			t.Errorf("Reconstruct(%#v): %v", data, err)
+			continue
+		}
+		if zot == nil || len(zot) != 0 {
+			t.Errorf("Reconstruct(%#v): want empty slice, got %#v", data, zot)
+		}
+	}
+}
+
+func TestFragmentChecked(t *testing.T) {
+	data := []byte("replicas")
+	for _, m := range []int{0, -1} {
+		if _, err := FragmentChecked(data, m); err != ErrInvalidM {
+			t.Errorf("FragmentChecked(data, %d): want %v got %v", m, ErrInvalidM, err)
+		}
+	}
+	f, err := FragmentChecked(data, 1)
+	if err != nil {
+		t.Fatalf("FragmentChecked(data, 1): %v", err)
+	}
+	zot, err := Reconstruct([]*Frag{f})
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+}
+
+func TestReconstructSurplus(t *testing.T) {
+	data := []byte("more than enough fragments, some of them useless")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// a fragment whose row is a multiple of another's makes the first m rows singular
+	a := slices.Clone(frags[0].A)
+	for i := range a {
+		a[i] = a[i].mul(2)
+	}
+	dup, err := FragmentWith(data, a)
+	if err != nil {
+		t.Fatalf("FragmentWith: %v", err)
+	}
+	zot, err := Reconstruct([]*Frag{frags[0], dup, frags[0], frags[4], frags[5]})
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+}
+
+func TestReconstructUsed(t *testing.T) {
+	data := []byte("which fragments did the work?")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	frags[1].Enc[0] ^= 1 // fails its CRC check
+	set := []*Frag{frags[0], frags[1], frags[0], frags[2], frags[5]}
+	zot, used, err := ReconstructUsed(set)
+	if err != nil {
+		t.Fatalf("ReconstructUsed: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("ReconstructUsed: want %q got %q", data, zot)
+	}
+	if want := []int{0, 3, 4}; !slices.Equal(used, want) {
+		t.Errorf("ReconstructUsed: want indices %v got %v", want, used)
+	}
+}
+
+func TestDecoderRows(t *testing.T) {
+	frags, err := Encode([]byte("rows of my own"), 3, 4)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	d, err := selectDecoder(frags)
+	if err != nil {
+		t.Fatalf("selectDecoder: %v", err)
+	}
+	for j, f := range d.frags {
+		if &d.a[j][0] == &f.A[0] {
+			t.Errorf("decoding matrix row %d shares fragment %d's A", j, d.used[j])
+		}
+	}
+}
+
+func TestFragsUnchanged(t *testing.T) {
+	data := []byte("the fragments are the caller's, and stay as they were")
+	frags, err := Encode(data, 4, 7)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	gf := NewEncoder(4, nil)
+	gf.Field = GF65536{}
+	gfrags, err := gf.Encode(data, 7)
+	if err != nil {
+		t.Fatalf("Encode in GF65536: %v", err)
+	}
+	for _, c := range []struct {
+		name  string
+		frags []*Frag
+		rec   func([]*Frag) ([]byte, error)
+	}{
+		{"Reconstruct", frags, Reconstruct},
+		{"ReconstructSecure", frags, ReconstructSecure},
+		{"ReconstructIn", gfrags, func(fs []*Frag) ([]byte, error) { return ReconstructIn(GF65536{}, fs) }},
+	} {
+		set := []*Frag{c.frags[6], c.frags[0], c.frags[3], c.frags[5]}
+		before := make([][]byte, len(set))
+		for i, f := range set {
+			before[i], _ = f.MarshalBinary()
+		}
+		zot, err := c.rec(set)
+		if err != nil || !bytes.Equal(zot, data) {
+			t.Errorf("%s: want %q got %q, %v", c.name, data, zot, err)
+		}
+		for i, f := range set {
+			if after, _ := f.MarshalBinary(); !bytes.Equal(after, before[i]) {
+				t.Errorf("%s changed fragment %d", c.name, i)
+			}
+		}
+	}
+}
+
+func TestReconstructRobust(t *testing.T) {
+	data := []byte("one bad apple, and a spare to replace it")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	frags[1].Enc[0] ^= 1
+	frags[1].CRC = frags[1].checksum() // corrupt, but undetectably so before decoding
+	if _, _, err := ReconstructUsed(frags); err != ErrDigestMismatch {
+		t.Fatalf("ReconstructUsed: want %v got %v", ErrDigestMismatch, err)
+	}
+	zot, used, err := ReconstructRobust(frags)
+	if err != nil {
+		t.Fatalf("ReconstructRobust: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("ReconstructRobust: want %q got %q", data, zot)
+	}
+	if len(used) != 3 || slices.Contains(used, 1) {
+		t.Errorf("ReconstructRobust: used %v, including the bad fragment", used)
+	}
+
+	// with no spare, or two bad fragments selected, there is nothing to be done
+	if _, _, err := ReconstructRobust(frags[0:3]); err != ErrDigestMismatch {
+		t.Errorf("ReconstructRobust without spares: want %v got %v", ErrDigestMismatch, err)
+	}
+	frags[2].Enc[0] ^= 1
+	frags[2].CRC = frags[2].checksum()
+	if _, _, err := ReconstructRobust(frags); err != ErrDigestMismatch {
+		t.Errorf("ReconstructRobust with two bad: want %v got %v", ErrDigestMismatch, err)
+	}
+
+	// other errors are returned at once
+	if _, _, err := ReconstructRobust(frags[0:2]); err != ErrTooFewFragments {
+		t.Errorf("ReconstructRobust of 2: want %v got %v", ErrTooFewFragments, err)
+	}
+}
+
+func TestReconstructFrom(t *testing.T) {
+	data := []byte("decoding starts when enough have come in")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	other, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	bad := frags[4].Clone()
+	bad.Enc[0] ^= 1 // fails its CRC check
+	send := func(fs ...*Frag) <-chan *Frag {
+		ch := make(chan *Frag) // unbuffered, so the sender waits for the rest to be discarded
+		go func() {
+			for _, f := range fs {
+				ch <- f
+			}
+			close(ch)
+		}()
+		return ch
+	}
+	zot, err := ReconstructFrom(send(other[0], nil, bad, frags[1], frags[1], other[1], frags[3], frags[5], frags[0], frags[2]), 3)
+	if err != nil {
+		t.Fatalf("ReconstructFrom: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("ReconstructFrom: want %q got %q", data, zot)
+	}
+	if _, err := ReconstructFrom(send(frags[0], other[1], frags[1], frags[0]), 3); err != ErrTooFewFragments {
+		t.Errorf("ReconstructFrom of too few: want %v got %v", ErrTooFewFragments, err)
+	}
+	if _, err := ReconstructFrom(send(), 0); err != ErrInvalidM {
+		t.Errorf("ReconstructFrom with m=0: want %v got %v", ErrInvalidM, err)
+	}
+}
+
+// benchSizes and benchMs are the data sizes and values of m for BenchmarkFragment and BenchmarkReconstruct.
+var (
+	benchSizes = []int{1 << 10, 64 << 10, 1 << 20}
+	benchMs    = []int{2, 4, 8, 16, 32}
+)
+
+// benchSize returns a short name for size n.
+func benchSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%dMiB", n>>20)
+	case n >= 1<<10:
+		return fmt.Sprintf("%dKiB", n>>10)
+	}
+	return fmt.Sprint(n)
+}
+
+func BenchmarkFragment(b *testing.B) {
+	for _, size := range benchSizes {
+		data := make([]byte, size)
+		rand.Read(data)
+		for _, m := range benchMs {
+			b.Run(fmt.Sprintf("m=%d/%s", m, benchSize(size)), func(b *testing.B) {
+				e := NewEncoder(m, rand.NewSource(79))
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					e.Fragment(data)
+				}
+			})
+		}
+	}
+}
+
+// reconstructSolve reconstructs the data from frags by Matrix.Solve with all the Enc columns as right-hand sides,
+// for comparison with Reconstruct.
+func reconstructSolve(frags []*Frag) ([]byte, error) {
+	d, err := selectDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	rhs := NewMatrix(d.m)
+	for j, f := range d.frags {
+		rhs[j] = make([]Field, d.fraglen)
+		for k, v := range f.Enc {
+			rhs[j][k] = Field(v)
+		}
+	}
+	x, err := d.a.Solve(rhs)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]Field, 0, d.m*d.fraglen)
+	for k := 0; k < d.fraglen; k++ {
+		for i := range x {
+			words = append(words, x[i][k])
+		}
+	}
+	return UnpackWords(words, int(d.frags[0].Len)), nil
+}
+
+func BenchmarkReconstruct(b *testing.B) {
+	for _, size := range benchSizes {
+		data := make([]byte, size)
+		rand.Read(data)
+		for _, m := range benchMs {
+			frags, err := NewEncoder(m, rand.NewSource(79)).Encode(data, m)
+			if err != nil {
+				b.Fatalf("Encode: %v", err)
+			}
+			// solve the system for all columns at once
+			b.Run(fmt.Sprintf("solve/m=%d/%s", m, benchSize(size)), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := reconstructSolve(frags); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			// as Reconstruct does
+			b.Run(fmt.Sprintf("reconstruct/m=%d/%s", m, benchSize(size)), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := Reconstruct(frags); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			// invert the matrix, then decode a column at a time, into a buffer
+			b.Run(fmt.Sprintf("invert/m=%d/%s", m, benchSize(size)), func(b *testing.B) {
+				out := make([]byte, len(data))
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := ReconstructInto(frags, out); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPackWords(t *testing.T) {
+	for _, c := range []struct {
+		data  []byte
+		words []Field
+	}{
+		{[]byte{}, []Field{}},
+		{[]byte{0x12, 0x34, 0xFF, 0x00}, []Field{0x1234, 0xFF00}},
+		{[]byte{0x12, 0x34, 0xAB}, []Field{0x1234, 0xAB00}},
+		{[]byte{0x01}, []Field{0x0100}},
+	} {
+		w := PackWords(c.data)
+		if !slices.Equal(w, c.words) {
+			t.Errorf("PackWords(%x): want %x got %x", c.data, c.words, w)
+		}
+		if out := UnpackWords(w, len(c.data)); !bytes.Equal(out, c.data) {
+			t.Errorf("UnpackWords(%x, %d): want %x got %x", w, len(c.data), c.data, out)
+		}
+	}
+	if out := UnpackWords([]Field{0x1234}, 3); !bytes.Equal(out, []byte{0x12, 0x34, 0}) {
+		t.Errorf("UnpackWords short: got %x", out)
+	}
+	data := make([]byte, 1001)
+	rand.New(rand.NewSource(1)).Read(data)
+	for n := range data {
+		if out := UnpackWords(PackWords(data[:n]), n); !bytes.Equal(out, data[:n]) {
+			t.Fatalf("length %d: pack then unpack is not the identity", n)
+		}
+	}
+}
+
+func TestPackWordsWidth(t *testing.T) {
+	for _, c := range []struct {
+		data  []byte
+		width int
+		words []Field
+	}{
+		{[]byte{0x12, 0x34, 0xAB}, 1, []Field{0x12, 0x34, 0xAB}},
+		{[]byte{0x12, 0x34, 0xAB}, 2, []Field{0x1234, 0xAB00}},
+		{[]byte{0x12, 0x34, 0xAB, 0xCD}, 3, []Field{0x1234AB, 0xCD0000}},
+		{[]byte{0x12, 0x34, 0xAB, 0xCD, 0xEF}, 3, []Field{0x1234AB, 0xCDEF00}},
+		{[]byte{0x12, 0x34, 0xAB, 0xCD, 0xEF}, 4, []Field{0x1234ABCD, 0xEF000000}},
+	} {
+		w := PackWordsWidth(c.data, c.width)
+		if !slices.Equal(w, c.words) {
+			t.Errorf("PackWordsWidth(%x, %d): want %x got %x", c.data, c.width, c.words, w)
+		}
+	}
+	data := make([]byte, 301)
+	rand.New(rand.NewSource(1)).Read(data)
+	for width := 1; width <= 4; width++ {
+		for n := range data {
+			w := PackWordsWidth(data[:n], width)
+			if out := UnpackWordsWidth(w, n, width); !bytes.Equal(out, data[:n]) {
+				t.Fatalf("width %d, length %d: pack then unpack is not the identity", width, n)
+			}
+			if width == 2 && !slices.Equal(w, PackWords(data[:n])) {
+				t.Fatalf("length %d: PackWordsWidth(2) differs from PackWords", n)
+			}
+		}
+	}
+	if out := UnpackWordsWidth([]Field{0x1234AB}, 5, 3); !bytes.Equal(out, []byte{0x12, 0x34, 0xAB, 0, 0}) {
+		t.Errorf("UnpackWordsWidth short: got %x", out)
+	}
+	for _, width := range []int{0, 5} {
+		func() {
+			defer func() {
+				if r := recover(); r != ErrWordWidth {
+					t.Errorf("PackWordsWidth width %d: want panic %v got %v", width, ErrWordWidth, r)
+				}
+			}()
+			PackWordsWidth(data, width)
+		}()
+	}
+}
+
+func TestResidues(t *testing.T) {
+	rnd := rand.New(rand.NewSource(62))
+	for _, m := range []int{1, 2, 3, 5, 8} {
+		for r := 0; r < 2*m; r++ {
+			for _, cols := range []int{0, 1, 4} {
+				n := cols*2*m + r
+				data := make([]byte, n)
+				rnd.Read(data)
+				frags, err := NewEncoder(m, rnd).Encode(data, m+1)
+				if err != nil {
+					t.Fatalf("m=%d len=%d: %v", m, n, err)
+				}
+				out, err := Reconstruct(frags[1:])
+				if err != nil || !bytes.Equal(out, data) {
+					t.Errorf("m=%d len=%d: Reconstruct: data mismatch (%v)", m, n, err)
+				}
+				var buf bytes.Buffer
+				if _, err := ReconstructStream(frags[0:m], &buf); err != nil || !bytes.Equal(buf.Bytes(), data) {
+					t.Errorf("m=%d len=%d: ReconstructStream: data mismatch (%v)", m, n, err)
+				}
+				if n > 0 {
+					out, err := ReconstructRange(frags[0:m], int64(n-1), 1)
+					if err != nil || !bytes.Equal(out, data[n-1:]) {
+						t.Errorf("m=%d len=%d: ReconstructRange: last byte mismatch (%v)", m, n, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMaxValEnc(t *testing.T) {
+	// 2×0x8000 + 3×0 = 65536 = MaxVal, the one Enc value that does not fit in 16 bits
+	data := []byte{0x80, 0x00, 0x00, 0x00, 0x12, 0x34}
+	dg := digest(data)
+	rows := [][]Field{{2, 3}, {1, 1}, {5, 7}}
+	frags := make([]*Frag, len(rows))
+	for i, a := range rows {
+		frags[i] = newFrag(int64(len(data)), a, encode(data, a), dg)
+	}
+	if frags[0].Enc[0] != int(MaxVal) {
+		t.Fatalf("Enc[0]: want %d got %d", MaxVal, frags[0].Enc[0])
+	}
+	if err := frags[0].Valid(); err != nil {
+		t.Fatalf("Valid: %v", err)
+	}
+	for _, sel := range [][]*Frag{frags[0:2], {frags[2], frags[0]}} {
+		out, err := Reconstruct(sel)
+		if err != nil || !bytes.Equal(out, data) {
+			t.Errorf("Reconstruct: want %x got %x (%v)", data, out, err)
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := ReconstructStream(frags[0:2], &buf); err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("ReconstructStream: want %x got %x (%v)", data, buf.Bytes(), err)
+	}
+	b, err := frags[0].MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	g := new(Frag)
+	if err := g.UnmarshalBinary(b); err != nil || !g.Equal(frags[0]) {
+		t.Errorf("binary round trip with MaxVal: %v", err)
+	}
+
+	// a fragment whose data word would be MaxVal is corrupt
+	one := newFrag(2, []Field{1}, []int{int(MaxVal)}, nil)
+	if _, err := Reconstruct([]*Frag{one}); !errors.Is(err, ErrCorruptOutput) {
+		t.Errorf("decoded MaxVal: want ErrCorruptOutput got %v", err)
+	}
+}
+
+func TestLeadingMismatchedM(t *testing.T) {
+	data := []byte("the first shall be last")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, m := range []int{2, 4} {
+		lead := frags[0].Clone()
+		lead.M = m
+		lead.CRC = 0
+		set := append([]*Frag{lead}, frags[1:]...)
+		out, err := Reconstruct(set)
+		if err != nil || !bytes.Equal(out, data) {
+			t.Errorf("M=%d: Reconstruct with a surplus: want data, got %q, %v", m, out, err)
+		}
+		_, err = Reconstruct(set[0:3])
+		var fe *FragmentError
+		if !errors.Is(err, ErrInconsistentMatrix) || !errors.As(err, &fe) || fe.Index != 0 {
+			t.Errorf("M=%d: Reconstruct: want ErrInconsistentMatrix in fragment 0, got %v", m, err)
+		}
+		_, err = ReconstructIn(GF65536{}, set[0:3])
+		if !errors.Is(err, ErrInconsistentMatrix) || !errors.As(err, &fe) || fe.Index != 0 {
+			t.Errorf("M=%d: ReconstructIn: want ErrInconsistentMatrix in fragment 0, got %v", m, err)
+		}
+	}
+}
+
+func TestMajorityParameters(t *testing.T) {
+	data := []byte("the majority decides, not the first")
+	frags, err := Encode(data, 3, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// a well-formed, correctly checksummed fragment of different data leads the set
+	other, err := Encode([]byte("short"), 2, 2)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	set := append([]*Frag{other[0]}, frags[1:]...)
+	out, err := Reconstruct(set)
+	if err != nil || !bytes.Equal(out, data) {
+		t.Errorf("Reconstruct: want %q got %q (%v)", data, out, err)
+	}
+	var buf bytes.Buffer
+	if _, err := ReconstructStream(set, &buf); err != nil || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("ReconstructStream: want %q got %q (%v)", data, buf.Bytes(), err)
+	}
+	if _, err := Reconstruct([]*Frag{nil, frags[1], frags[2], frags[3]}); err != nil {
+		t.Errorf("Reconstruct with nil leader: %v", err)
+	}
+	if _, err := Reconstruct([]*Frag{other[0], other[1], frags[1], frags[2]}); !errors.Is(err, ErrUnstableParameters) {
+		t.Errorf("Reconstruct with a tie: want ErrUnstableParameters got %v", err)
+	}
+}
+
+// roundTrip encodes data as n fragments with at least m needed, and checks that
+// each of the given subsets of them reconstructs the data exactly.
+func roundTrip(t *testing.T, rnd *rand.Rand, data []byte, m, n int, subsets [][]int) {
+	t.Helper()
+	frags, err := NewEncoder(m, rnd).Encode(data, n)
+	if err != nil {
+		t.Fatalf("len=%d m=%d n=%d: Encode: %v", len(data), m, n, err)
+	}
+	for _, sub := range subsets {
+		set := make([]*Frag, len(sub))
+		for i, j := range sub {
+			set[i] = frags[j]
+		}
+		out, err := Reconstruct(set)
+		if err != nil || !bytes.Equal(out, data) {
+			t.Errorf("len=%d m=%d n=%d subset %v: data mismatch (%v)", len(data), m, n, sub, err)
+		}
+	}
+}
+
+func TestRoundTripTable(t *testing.T) {
+	rnd := rand.New(rand.NewSource(66))
+	for _, c := range []struct {
+		len, m int
+	}{
+		{0, 1}, {1, 1}, {2, 1}, {1, 2}, {3, 2}, {4, 2}, {31, 16}, {32, 16}, {33, 16}, {4095, 7}, {4096, 16},
+	} {
+		data := make([]byte, c.len)
+		rnd.Read(data)
+		n := 2 * c.m
+		var subsets [][]int
+		if n <= 8 {
+			subsets = combinations(n, c.m) // every sufficient subset
+		} else {
+			for i := 0; i < 20; i++ {
+				subsets = append(subsets, rnd.Perm(n)[:c.m])
+			}
+		}
+		roundTrip(t, rnd, data, c.m, n, subsets)
+	}
+}
+
+func TestRoundTripRandom(t *testing.T) {
+	trials := 200
+	if testing.Short() {
+		trials = 20
+	}
+	rnd := rand.New(rand.NewSource(67))
+	for i := 0; i < trials; i++ {
+		m := 1 + rnd.Intn(16)
+		n := 2 * m
+		data := make([]byte, rnd.Intn(4097))
+		rnd.Read(data)
+		subsets := [][]int{rnd.Perm(n)[:m], rnd.Perm(n)[:m+rnd.Intn(m+1)]}
+		roundTrip(t, rnd, data, m, n, subsets)
+	}
+}
+
+// combinations returns all k-element subsets of 0..n-1, in lexicographic order.
+func combinations(n, k int) [][]int {
+	var out [][]int
+	var c []int
+	var gen func(i int)
+	gen = func(i int) {
+		if len(c) == k {
+			out = append(out, slices.Clone(c))
+			return
+		}
+		for j := i; j < n; j++ {
+			c = append(c, j)
+			gen(j + 1)
+			c = c[:len(c)-1]
+		}
+	}
+	gen(0)
+	return out
+}
+
+func TestNoAliasing(t *testing.T) {
+	orig := []byte("this buffer is reused as soon as the call returns")
+	var buf []byte
+	scribble := func() {
+		for i := range buf {
+			buf[i] = ^buf[i]
+		}
+	}
+	buf = slices.Clone(orig)
+	f := Fragment(buf, 3)
+	g := f.Clone()
+	scribble()
+	if !f.Equal(g) || !bytes.Equal(f.Digest, g.Digest) {
+		t.Errorf("Fragment: fragment changed with its input")
+	}
+	buf = slices.Clone(orig)
+	frags, err := Encode(buf, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	scribble()
+	buf = slices.Clone(orig)
+	e := NewEncoder(3, rand.NewSource(75))
+	e.N = 5
+	if err := e.Append(buf); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	scribble()
+	blocks := e.Finish()
+	for _, set := range [][]*Frag{frags, blocks} {
+		out, err := Reconstruct(set)
+		if err != nil || !bytes.Equal(out, orig) {
+			t.Errorf("fragments changed with their input: got %q (%v)", out, err)
+		}
+	}
+
+	// fragments of a set share nothing
+	a1, d1 := slices.Clone(frags[1].A), slices.Clone(frags[1].Digest)
+	frags[0].A[0]++
+	frags[0].Digest[0]++
+	if !slices.Equal(frags[1].A, a1) || !bytes.Equal(frags[1].Digest, d1) {
+		t.Errorf("fragments share A or Digest")
+	}
+
+	// the output buffer is the caller's once ReconstructInto returns
+	dst := make([]byte, 100)
+	n, err := ReconstructInto(frags[1:4], dst)
+	if err != nil || !bytes.Equal(dst[0:n], orig) {
+		t.Fatalf("ReconstructInto: %v", err)
+	}
+	clear(dst)
+	if out, err := Reconstruct(frags[2:5]); err != nil || !bytes.Equal(out, orig) {
+		t.Errorf("Reconstruct after reusing dst: %v", err)
+	}
+}
+
+func TestSetID(t *testing.T) {
+	data := []byte("two encodings of the same data")
+	one, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	two, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, set := range [][]*Frag{one, two} {
+		if set[0].SetID == 0 {
+			t.Fatalf("fragments have no SetID")
+		}
+		for _, f := range set {
+			if f.SetID != set[0].SetID || !f.Verify() {
+				t.Fatalf("fragment %d: SetID %x, set %x, CRC ok %v", f.Index, f.SetID, set[0].SetID, f.Verify())
+			}
+		}
+	}
+	if one[0].SetID == two[0].SetID {
+		t.Fatalf("two sets share a SetID")
+	}
+	mixed := []*Frag{one[0], two[1], one[2], two[3], one[4]}
+	good, dropped, err := ConsistentReport(mixed)
+	if err != nil || len(good) != 3 || len(dropped) != 2 || dropped[0] != (DropInfo{1, DropSetID}) {
+		t.Errorf("ConsistentReport: got %d good, dropped %v, %v", len(good), dropped, err)
+	}
+	if out, err := Reconstruct(mixed); err != nil || !bytes.Equal(out, data) {
+		t.Errorf("Reconstruct with surplus: %v", err)
+	}
+	_, err = Reconstruct([]*Frag{one[0], two[1], one[2]})
+	var fe *FragmentError
+	if !errors.Is(err, ErrMixedSets) || !errors.As(err, &fe) || fe.Index != 1 {
+		t.Errorf("Reconstruct of mixed sets: want ErrMixedSets for 1, got %v", err)
+	}
+
+	// fragments without a SetID combine with anything
+	old := one[1].Clone()
+	old.SetID = 0
+	old.CRC = old.checksum()
+	if out, err := Reconstruct([]*Frag{one[0], old, one[2]}); err != nil || !bytes.Equal(out, data) {
+		t.Errorf("Reconstruct with a fragment lacking SetID: %v", err)
+	}
+	f, err := Repair(one[1:4], one[0].A)
+	if err != nil || f.SetID != one[0].SetID || !f.Verify() {
+		t.Errorf("Repair: SetID %x want %x (%v)", f.SetID, one[0].SetID, err)
+	}
+}
+
+func TestCorruptOutputError(t *testing.T) {
+	data := make([]byte, 40)
+	frags, err := NewEncoder(2, rand.NewSource(78)).Encode(data, 2)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// make column 3 decode to the words (0, MaxVal): Enc[k] = A·w
+	for _, f := range frags {
+		f.Enc[3] = int(f.A[1].mul(MaxVal))
+		f.CRC = 0
+	}
+	sys, err := SystematicEncode(data, 2, 2)
+	if err != nil {
+		t.Fatalf("SystematicEncode: %v", err)
+	}
+	sys[1].Enc[3] = int(MaxVal)
+	sys[1].CRC = 0
+	for _, set := range [][]*Frag{frags, sys} {
+		_, err := Reconstruct(set)
+		var ce *CorruptOutputError
+		if !errors.As(err, &ce) || !errors.Is(err, ErrCorruptOutput) || ce.Column != 3 || ce.Word != 1 {
+			t.Errorf("Reconstruct: want column 3 word 1, got %v", err)
+		}
+		var buf bytes.Buffer
+		if _, err := ReconstructStream(set, &buf); !errors.As(err, &ce) || ce.Column != 3 || ce.Word != 1 {
+			t.Errorf("ReconstructStream: want column 3 word 1, got %v", err)
+		}
+	}
+}
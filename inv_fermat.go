@@ -0,0 +1,10 @@
+//go:build idanotab || crypto
+
+package ida
+
+// inv returns the multiplicative inverse of a, computed on demand instead of from a table.
+// It is also used with the crypto build tag: the exponent is fixed and mul has no branches,
+// so the time taken does not depend on a, as an index into the table would (through the cache).
+func (a Field) inv() Field {
+	return a.inverse()
+}
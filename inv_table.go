@@ -0,0 +1,8 @@
+//go:build !idanotab && !crypto
+
+package ida
+
+// inv returns the multiplicative inverse of a, from the table generated in zptab.go.
+func (a Field) inv() Field {
+	return invtab[a]
+}
@@ -0,0 +1,17 @@
+//go:build !idanotab && !crypto
+
+package ida
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyBadTable(t *testing.T) {
+	save := invtab[1234]
+	invtab[1234]++
+	defer func() { invtab[1234] = save }()
+	if err := VerifyTables(); !errors.Is(err, ErrBadTable) {
+		t.Errorf("VerifyTables with a bad entry: want %v got %v", ErrBadTable, err)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build go1.23
+
+package ida
+
+import (
+	"iter"
+	"slices"
+)
+
+// FragmentIter returns an iterator over n fragments of data, any m of which are normally enough to reconstruct it,
+// as for [Encoder.FragmentIter] with the default source of random numbers.
+func FragmentIter(data []byte, m, n int) (iter.Seq2[int, *Frag], error) {
+	return NewEncoder(m, nil).FragmentIter(data, n)
+}
+
+// FragmentIter returns an iterator over the fragments that Encode(data, n) would return,
+// yielding i and fragment i, for i from 0 to n-1, encoding each only when it is reached,
+// so that each can be written out and discarded before the next is made.
+// The encoding rows are drawn when iteration starts; each iteration makes a new set of fragments.
+// Like Encode, it retains no reference to data once iteration stops, but data must not change before then.
+// It returns an error, as Encode does, if e.M < 1 or n < e.M;
+// if e is changed so that they no longer hold before iteration starts, the iterator yields nothing.
+func (e *Encoder) FragmentIter(data []byte, n int) (iter.Seq2[int, *Frag], error) {
+	if err := e.check(n); err != nil {
+		return nil, err
+	}
+	return func(yield func(int, *Frag) bool) {
+		if e.check(n) != nil {
+			return
+		}
+		dg := digest(data)
+		rows, id := e.rows(n), e.setID()
+		for i, a := range rows {
+			f := newFrag(int64(len(data)), a, e.encode(data, a), slices.Clone(dg))
+			f.member(i+1, id)
+			if !yield(i, f) {
+				return
+			}
+		}
+	}, nil
+}
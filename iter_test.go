@@ -0,0 +1,67 @@
+//go:build go1.23
+
+package ida
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestFragmentIter(t *testing.T) {
+	data := []byte("one at a time, please")
+	want, err := NewEncoder(3, rand.NewSource(76)).Encode(data, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	seq, err := NewEncoder(3, rand.NewSource(76)).FragmentIter(data, 6)
+	if err != nil {
+		t.Fatalf("FragmentIter: %v", err)
+	}
+	var frags []*Frag
+	for i, f := range seq {
+		if i != len(frags) || f.Index != i+1 {
+			t.Errorf("fragment %d: got number %d, Index %d", len(frags), i, f.Index)
+		}
+		if !f.Equal(want[i]) || f.CRC != want[i].CRC {
+			t.Errorf("fragment %d differs from Encode's", i)
+		}
+		frags = append(frags, f)
+	}
+	if len(frags) != 6 {
+		t.Fatalf("want 6 fragments got %d", len(frags))
+	}
+	out, err := Reconstruct(frags[3:])
+	if err != nil || !bytes.Equal(out, data) {
+		t.Errorf("Reconstruct: want %q got %q (%v)", data, out, err)
+	}
+	n := 0
+	seq, err = FragmentIter(data, 3, 6)
+	if err != nil {
+		t.Fatalf("FragmentIter: %v", err)
+	}
+	for range seq {
+		if n++; n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Errorf("break: %d fragments", n)
+	}
+	if _, err := FragmentIter(data, 3, 2); err != ErrInvalidN {
+		t.Errorf("n < m: want %v got %v", ErrInvalidN, err)
+	}
+	if _, err := FragmentIter(data, 0, 2); err != ErrInvalidM {
+		t.Errorf("m < 1: want %v got %v", ErrInvalidM, err)
+	}
+	// an Encoder made invalid after the check yields nothing, rather than panicking
+	e := NewEncoder(3, nil)
+	seq, err = e.FragmentIter(data, 6)
+	if err != nil {
+		t.Fatalf("FragmentIter: %v", err)
+	}
+	e.M = 0
+	for range seq {
+		t.Errorf("invalid Encoder: fragment yielded")
+	}
+}
@@ -0,0 +1,507 @@
+package ida
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// The binary encoding of a Frag is a header of the magic string "ida", a version byte,
+// and the length of the remaining body as a 32-bit value, followed by the body:
+// a flags byte; M, Len and Index as unsigned varints; CRC as a 32-bit value;
+// the length of Digest as an unsigned varint, and Digest itself;
+// if the flags include encBlocks, the number of Blocks and their values as unsigned varints;
+// if the flags include encSetID, SetID as a 64-bit value;
+// if the flags include encMAC, the length of MAC as an unsigned varint, and MAC itself;
+// if the flags include encPad, Pad as an unsigned varint;
+// the length of Enc as an unsigned varint; the elements of A as 32-bit values;
+// and the elements of Enc, as 16-bit values if the flags include encWords16 (as they do when
+// no element is MaxVal), and as 32-bit values otherwise.
+// All fixed-size values are little-endian.
+//
+// Len was an int before it became an int64. Its varint encoding is the same either way,
+// so fragments encoded by MarshalBinary, GobEncode or MarshalJSON before that still decode,
+// and those encoded since decode with older versions if Len fits their int.
+// That does not extend to gob streams written before Frag had GobEncode, which no longer decode at all
+// (see [Frag.GobDecode]).
+
+const (
+	binMagic   = "ida"
+	binVersion = 1
+	binHeader  = len(binMagic) + 1 + 4
+
+	encWords16 = 1 << 0 // Enc values are 16 bits
+	encBlocks  = 1 << 1 // Blocks are present
+	encSetID   = 1 << 2 // SetID is present
+	encMAC     = 1 << 3 // MAC is present
+	encPad     = 1 << 4 // Pad is present
+
+	encKnown = encWords16 | encBlocks | encSetID | encMAC | encPad // flags this version understands
+)
+
+var (
+	ErrBadEncoding = errors.New("invalid fragment encoding")
+	ErrTooLarge    = errors.New("fragment data length exceeds MaxLen")
+)
+
+// MaxLen, if positive, is the largest data length Len that UnmarshalBinary, GobDecode, ReadFrom
+// and UnmarshalJSON will accept, so that a fragment from an untrusted source
+// cannot make its receiver allocate for absurd amounts of data later.
+// (Decoding itself allocates no more than the size of the encoding.)
+var MaxLen int64
+
+// MarshalBinary returns the binary encoding of f.
+func (f *Frag) MarshalBinary() ([]byte, error) {
+	if f.M < 0 || f.Len < 0 || f.Index < 0 || f.Pad < 0 {
+		return nil, fmt.Errorf("%w: negative parameter", ErrBadEncoding)
+	}
+	flags := byte(encWords16)
+	for _, v := range f.Enc {
+		if v > 0xFFFF {
+			flags &^= encWords16
+			break
+		}
+	}
+	if f.Blocks != nil {
+		flags |= encBlocks
+	}
+	if f.SetID != 0 {
+		flags |= encSetID
+	}
+	if f.MAC != nil {
+		flags |= encMAC
+	}
+	if f.Pad != 0 {
+		flags |= encPad
+	}
+	buf := make([]byte, binHeader, binHeader+40+len(f.Digest)+10*len(f.Blocks)+len(f.MAC)+4*len(f.A)+4*len(f.Enc))
+	copy(buf, binMagic)
+	buf[len(binMagic)] = binVersion
+	buf = append(buf, flags)
+	buf = binary.AppendUvarint(buf, uint64(f.M))
+	buf = binary.AppendUvarint(buf, uint64(f.Len))
+	buf = binary.AppendUvarint(buf, uint64(f.Index))
+	buf = binary.LittleEndian.AppendUint32(buf, f.CRC)
+	buf = binary.AppendUvarint(buf, uint64(len(f.Digest)))
+	buf = append(buf, f.Digest...)
+	if flags&encBlocks != 0 {
+		buf = binary.AppendUvarint(buf, uint64(len(f.Blocks)))
+		for _, v := range f.Blocks {
+			if v < 0 {
+				return nil, fmt.Errorf("%w: negative parameter", ErrBadEncoding)
+			}
+			buf = binary.AppendUvarint(buf, uint64(v))
+		}
+	}
+	if flags&encSetID != 0 {
+		buf = binary.LittleEndian.AppendUint64(buf, f.SetID)
+	}
+	if flags&encMAC != 0 {
+		buf = binary.AppendUvarint(buf, uint64(len(f.MAC)))
+		buf = append(buf, f.MAC...)
+	}
+	if flags&encPad != 0 {
+		buf = binary.AppendUvarint(buf, uint64(f.Pad))
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(f.Enc)))
+	for _, v := range f.A {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+	}
+	for _, v := range f.Enc {
+		if flags&encWords16 != 0 {
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(v))
+		} else {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+		}
+	}
+	body := len(buf) - binHeader
+	if uint64(body) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: fragment too large", ErrBadEncoding)
+	}
+	binary.LittleEndian.PutUint32(buf[len(binMagic)+1:], uint32(body))
+	return buf, nil
+}
+
+// Size returns the length of f's binary encoding, as MarshalBinary would produce it, without encoding f.
+func (f *Frag) Size() int {
+	width := 2
+	if slices.ContainsFunc(f.Enc, func(v int) bool { return v > 0xFFFF }) {
+		width = 4
+	}
+	return f.size(len(f.Enc), width)
+}
+
+// size returns the length of f's binary encoding if it had nenc Enc values of the given width in bytes.
+func (f *Frag) size(nenc, width int) int {
+	n := binHeader + 1 + uvlen(uint64(f.M)) + uvlen(uint64(f.Len)) + uvlen(uint64(f.Index)) + 4
+	n += uvlen(uint64(len(f.Digest))) + len(f.Digest)
+	if f.Blocks != nil {
+		n += uvlen(uint64(len(f.Blocks)))
+		for _, v := range f.Blocks {
+			n += uvlen(uint64(v))
+		}
+	}
+	if f.SetID != 0 {
+		n += 8
+	}
+	if f.MAC != nil {
+		n += uvlen(uint64(len(f.MAC))) + len(f.MAC)
+	}
+	if f.Pad != 0 {
+		n += uvlen(uint64(f.Pad))
+	}
+	return n + uvlen(uint64(nenc)) + 4*len(f.A) + width*nenc
+}
+
+// Overhead returns the total size of the binary encodings of n fragments of data of length dlen,
+// any m of which can reconstruct it, as made by [Encode], so that the cost of a choice of m and n
+// can be compared with dlen without encoding anything.
+// It assumes the Enc values all fit in 16 bits, as they almost always do;
+// a fragment with a value of MaxVal takes twice the space for its Enc.
+func Overhead(dlen, m, n int) int {
+	nenc := int(enclen(int64(dlen), m))
+	total := 0
+	for i := 1; i <= n; i++ {
+		f := Frag{Len: int64(dlen), M: m, A: make([]Field, m), Index: i, SetID: 1, Digest: make([]byte, sha256.Size)}
+		total += f.size(nenc, 2)
+	}
+	return total
+}
+
+// uvlen returns the length of the unsigned varint encoding of v.
+func uvlen(v uint64) int {
+	n := 1
+	for ; v >= 0x80; v >>= 7 {
+		n++
+	}
+	return n
+}
+
+// UnmarshalBinary sets f to the Frag with the given binary encoding, as produced by MarshalBinary.
+// It returns an error, leaving f unchanged, if the encoding is truncated or inconsistent,
+// has flags it does not know, or if the resulting fragment is not Valid.
+// Every allocation is bounded by the length of data, whatever lengths the encoding claims.
+func (f *Frag) UnmarshalBinary(data []byte) error {
+	if len(data) < binHeader || string(data[0:len(binMagic)]) != binMagic {
+		return fmt.Errorf("%w: not a fragment", ErrBadEncoding)
+	}
+	if v := data[len(binMagic)]; v != binVersion {
+		return fmt.Errorf("%w: unknown version %d", ErrBadEncoding, v)
+	}
+	body := binary.LittleEndian.Uint32(data[len(binMagic)+1:])
+	if uint64(len(data)-binHeader) != uint64(body) {
+		return fmt.Errorf("%w: length %d, header says %d", ErrBadEncoding, len(data)-binHeader, body)
+	}
+	d := decbuf{b: data[binHeader:]}
+	flags := d.byte()
+	if flags&^encKnown != 0 {
+		return fmt.Errorf("%w: unknown flags %#x", ErrBadEncoding, flags&^encKnown)
+	}
+	m := d.int()
+	dlen := d.int64()
+	index := d.int()
+	crc := d.uint32()
+	var dg []byte
+	if n := d.int(); n > 0 {
+		dg = append([]byte(nil), d.bytes(n)...)
+	}
+	var blocks []int64
+	if flags&encBlocks != 0 {
+		nb := d.int()
+		if nb > len(d.b) {
+			return fmt.Errorf("%w: inconsistent lengths", ErrBadEncoding)
+		}
+		blocks = make([]int64, nb)
+		for i := range blocks {
+			blocks[i] = d.int64()
+		}
+	}
+	var setid uint64
+	if flags&encSetID != 0 {
+		setid = d.uint64()
+	}
+	var mac []byte
+	if flags&encMAC != 0 {
+		mac = append([]byte{}, d.bytes(d.int())...)
+	}
+	var pad int
+	if flags&encPad != 0 {
+		pad = d.int()
+	}
+	nenc := d.int()
+	if d.err != nil {
+		return d.err
+	}
+	if MaxLen > 0 && dlen > MaxLen {
+		return fmt.Errorf("%w: %d", ErrTooLarge, dlen)
+	}
+	if m < 1 || int64(nenc)-enclen(dlen, m) != int64(pad) {
+		return fmt.Errorf("%w: inconsistent lengths", ErrBadEncoding)
+	}
+	if blocks != nil && badblocks(blocks, dlen) {
+		return fmt.Errorf("%w: block lengths do not sum to Len", ErrBadEncoding)
+	}
+	width := 4
+	if flags&encWords16 != 0 {
+		width = 2
+	}
+	if m < 1 || m > len(d.b)/4 || nenc > (len(d.b)-4*m)/width || len(d.b) != 4*m+width*nenc {
+		return fmt.Errorf("%w: inconsistent lengths", ErrBadEncoding)
+	}
+	a := make([]Field, m)
+	for i := range a {
+		a[i] = Field(d.uint32())
+	}
+	enc := make([]int, nenc)
+	for i := range enc {
+		if width == 2 {
+			enc[i] = int(d.uint16())
+		} else {
+			enc[i] = int(d.uint32())
+		}
+	}
+	nf := &Frag{Len: dlen, M: m, A: a, Enc: enc, Index: index, SetID: setid, CRC: crc, Digest: dg, Blocks: blocks, MAC: mac, Pad: pad}
+	if err := nf.Valid(); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadEncoding, err)
+	}
+	*f = *nf
+	return nil
+}
+
+// GobEncode returns the binary encoding of f, so that gob stores fragments compactly,
+// and in a form that does not change as the struct does.
+func (f *Frag) GobEncode() ([]byte, error) {
+	return f.MarshalBinary()
+}
+
+// GobDecode sets f to the Frag with the given binary encoding, which is checked as for UnmarshalBinary.
+// Gob streams written before Frag had GobEncode, with gob's own encoding of the struct, cannot be decoded into a Frag:
+// gob rejects them before GobDecode is called. Such a stream can be decoded into a struct with the old members
+// (Len and M as int, A as []Field, and Enc as []int), and the values copied into Frags.
+func (f *Frag) GobDecode(data []byte) error {
+	return f.UnmarshalBinary(data)
+}
+
+// WriteTo writes the binary encoding of f to w, returning the number of bytes written.
+func (f *Frag) WriteTo(w io.Writer) (int64, error) {
+	buf, err := f.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// ReadFrom sets f to the Frag whose binary encoding is read from r,
+// reading exactly that encoding and no further, and returning the number of bytes read.
+// It returns io.EOF if r is at end of file, and io.ErrUnexpectedEOF if the encoding is truncated.
+// The encoding is checked as for UnmarshalBinary.
+func (f *Frag) ReadFrom(r io.Reader) (int64, error) {
+	hdr := make([]byte, binHeader)
+	n, err := io.ReadFull(r, hdr)
+	if err != nil {
+		return int64(n), err
+	}
+	if string(hdr[0:len(binMagic)]) != binMagic {
+		return int64(n), fmt.Errorf("%w: not a fragment", ErrBadEncoding)
+	}
+	body := int64(binary.LittleEndian.Uint32(hdr[len(binMagic)+1:]))
+	// the buffer grows as the body arrives, not to the length claimed in the header
+	var buf bytes.Buffer
+	buf.Grow(binHeader + int(min(body, 64*1024)))
+	buf.Write(hdr)
+	nb, err := io.CopyN(&buf, r, body)
+	n += int(nb)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return int64(n), err
+	}
+	return int64(n), f.UnmarshalBinary(buf.Bytes())
+}
+
+// jsonFrag is the JSON form of a Frag, with A and Enc held as little-endian 32-bit values,
+// or 16-bit values for Enc when they fit, encoded in base64.
+type jsonFrag struct {
+	Len    int64
+	M      int
+	Index  int     `json:",omitempty"`
+	SetID  uint64  `json:",omitempty"`
+	CRC    uint32  `json:",omitempty"`
+	Digest []byte  `json:",omitempty"`
+	Blocks []int64 `json:",omitempty"`
+	Words  int64   `json:",omitempty"`
+	MAC    []byte  `json:",omitempty"`
+	A      []byte
+	Enc    []byte
+}
+
+// MarshalJSON returns the JSON encoding of f, an object with members Len, M, Index, SetID (if any), CRC,
+// Digest (in base64), Blocks (if any), Words (the number of Enc values, only if f has Pad), MAC (if any),
+// and A and Enc, in base64 of their little-endian representation
+// as 32-bit values, or for Enc, 16-bit values if they all fit.
+func (f *Frag) MarshalJSON() ([]byte, error) {
+	jf := jsonFrag{Len: f.Len, M: f.M, Index: f.Index, SetID: f.SetID, CRC: f.CRC, Digest: f.Digest, Blocks: f.Blocks, MAC: f.MAC}
+	if f.Pad != 0 {
+		jf.Words = int64(len(f.Enc))
+	}
+	jf.A = make([]byte, 0, 4*len(f.A))
+	for _, v := range f.A {
+		jf.A = binary.LittleEndian.AppendUint32(jf.A, uint32(v))
+	}
+	wide := slices.ContainsFunc(f.Enc, func(v int) bool { return v > 0xFFFF })
+	jf.Enc = make([]byte, 0, 4*len(f.Enc))
+	for _, v := range f.Enc {
+		if wide {
+			jf.Enc = binary.LittleEndian.AppendUint32(jf.Enc, uint32(v))
+		} else {
+			jf.Enc = binary.LittleEndian.AppendUint16(jf.Enc, uint16(v))
+		}
+	}
+	return json.Marshal(&jf)
+}
+
+// UnmarshalJSON sets f to the Frag with the given JSON encoding, as produced by MarshalJSON.
+// It returns an error, leaving f unchanged, if the lengths of A and Enc are inconsistent with M and Len,
+// or if the resulting fragment is not Valid.
+// As for UnmarshalBinary, the lengths are checked before anything is allocated for A and Enc.
+func (f *Frag) UnmarshalJSON(data []byte) error {
+	var jf jsonFrag
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return err
+	}
+	if MaxLen > 0 && jf.Len > MaxLen {
+		return fmt.Errorf("%w: %d", ErrTooLarge, jf.Len)
+	}
+	if jf.M < 1 || jf.Len < 0 || len(jf.A)%4 != 0 || len(jf.A)/4 != jf.M || jf.Blocks != nil && badblocks(jf.Blocks, jf.Len) {
+		return fmt.Errorf("%w: inconsistent lengths", ErrBadEncoding)
+	}
+	nenc := enclen(jf.Len, jf.M)
+	pad := 0
+	if jf.Words != 0 {
+		if jf.Words < nenc || jf.Words > int64(len(jf.Enc)/2) {
+			return fmt.Errorf("%w: inconsistent lengths", ErrBadEncoding)
+		}
+		pad = int(jf.Words - nenc)
+		nenc = jf.Words
+	}
+	// divide the length of Enc, rather than multiply nenc, which comes from the input and could overflow
+	width := 2
+	switch {
+	case len(jf.Enc)%2 == 0 && int64(len(jf.Enc)/2) == nenc:
+	case len(jf.Enc)%4 == 0 && int64(len(jf.Enc)/4) == nenc:
+		width = 4
+	default:
+		return fmt.Errorf("%w: inconsistent lengths", ErrBadEncoding)
+	}
+	a := make([]Field, jf.M)
+	for i := range a {
+		a[i] = Field(binary.LittleEndian.Uint32(jf.A[4*i:]))
+	}
+	enc := make([]int, nenc)
+	for i := range enc {
+		if width == 2 {
+			enc[i] = int(binary.LittleEndian.Uint16(jf.Enc[2*i:]))
+		} else {
+			enc[i] = int(binary.LittleEndian.Uint32(jf.Enc[4*i:]))
+		}
+	}
+	nf := &Frag{Len: jf.Len, M: jf.M, A: a, Enc: enc, Index: jf.Index, SetID: jf.SetID, CRC: jf.CRC, Digest: jf.Digest, Blocks: jf.Blocks, MAC: jf.MAC, Pad: pad}
+	if err := nf.Valid(); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadEncoding, err)
+	}
+	*f = *nf
+	return nil
+}
+
+// decbuf consumes values from a buffer, recording the first error.
+type decbuf struct {
+	b   []byte
+	err error
+}
+
+func (d *decbuf) short() {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: truncated", ErrBadEncoding)
+	}
+	d.b = nil
+}
+
+func (d *decbuf) bytes(n int) []byte {
+	if n > len(d.b) {
+		d.short()
+		return nil
+	}
+	v := d.b[0:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decbuf) byte() byte {
+	if v := d.bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (d *decbuf) uint16() uint16 {
+	if v := d.bytes(2); v != nil {
+		return binary.LittleEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (d *decbuf) uint32() uint32 {
+	if v := d.bytes(4); v != nil {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (d *decbuf) uint64() uint64 {
+	if v := d.bytes(8); v != nil {
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+// int64 consumes an unsigned varint that must fit in an int64.
+func (d *decbuf) int64() int64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.short()
+		return 0
+	}
+	d.b = d.b[n:]
+	if v > math.MaxInt64 {
+		if d.err == nil {
+			d.err = fmt.Errorf("%w: value too large", ErrBadEncoding)
+		}
+		return 0
+	}
+	return int64(v)
+}
+
+// int consumes an unsigned varint that must fit in an int.
+func (d *decbuf) int() int {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.short()
+		return 0
+	}
+	d.b = d.b[n:]
+	if v > math.MaxInt {
+		if d.err == nil {
+			d.err = fmt.Errorf("%w: value too large", ErrBadEncoding)
+		}
+		return 0
+	}
+	return int(v)
+}
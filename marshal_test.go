@@ -0,0 +1,413 @@
+package ida
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/iotest"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	data := []byte("fragments on the wire, without gob's overhead")
+	frags, err := Encode(data, 4, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	wide := frags[5]
+	wide.Enc[0] = int(MaxVal) // needs 32-bit Enc words
+	empty, _ := FragmentWith(nil, []Field{1, 2})
+	empty.Digest = nil
+	for i, f := range append(frags, empty) {
+		buf, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatalf("fragment %d: MarshalBinary: %v", i, err)
+		}
+		var g Frag
+		if err := g.UnmarshalBinary(buf); err != nil {
+			t.Fatalf("fragment %d: UnmarshalBinary: %v", i, err)
+		}
+		if !reflect.DeepEqual(f, &g) {
+			t.Errorf("fragment %d: want %#v got %#v", i, f, &g)
+		}
+		for n := 0; n < len(buf); n++ {
+			if err := g.UnmarshalBinary(buf[0:n]); !errors.Is(err, ErrBadEncoding) {
+				t.Errorf("fragment %d: truncated to %d: want %v got %v", i, n, ErrBadEncoding, err)
+			}
+		}
+	}
+	buf, _ := frags[0].MarshalBinary()
+	clear(buf[len(buf)-4*frags[0].M-2*len(frags[0].Enc):][0:4]) // zero element in A
+	var g Frag
+	if err := g.UnmarshalBinary(buf); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("bad A value: want %v got %v", ErrBadEncoding, err)
+	}
+	// a flag from a later version cannot be skipped, so it is rejected
+	buf, _ = frags[0].MarshalBinary()
+	buf[binHeader] |= 1 << 7
+	if err := g.UnmarshalBinary(buf); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("unknown flag: want %v got %v", ErrBadEncoding, err)
+	}
+}
+
+func TestGob(t *testing.T) {
+	data := []byte("gobbed fragments")
+	frags, err := Encode(data, 2, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(frags); err != nil {
+		t.Fatalf("gob Encode: %v", err)
+	}
+	var got []*Frag
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("gob Decode: %v", err)
+	}
+	if !reflect.DeepEqual(frags, got) {
+		t.Errorf("gob: want %#v got %#v", frags, got)
+	}
+	// a malicious blob must not produce a fragment
+	bad, _ := frags[0].MarshalBinary()
+	clear(bad[len(bad)-4*frags[0].M-2*len(frags[0].Enc):][0:4]) // zero element in A
+	var f Frag
+	if err := f.GobDecode(bad); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("GobDecode of bad fragment: want %v got %v", ErrBadEncoding, err)
+	}
+}
+
+// TestGobBaseline pins the incompatibility with gob streams written before Frag had GobEncode,
+// and checks the way around it given by GobDecode's comment.
+func TestGobBaseline(t *testing.T) {
+	old, err := os.ReadFile("testdata/baseline.gob")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var frags []*Frag
+	if err := gob.NewDecoder(bytes.NewReader(old)).Decode(&frags); err == nil {
+		t.Errorf("gob Decode of a struct-encoded stream: want error")
+	}
+	var legacy []*struct {
+		Len int
+		M   int
+		A   []Field
+		Enc []int
+	}
+	if err := gob.NewDecoder(bytes.NewReader(old)).Decode(&legacy); err != nil {
+		t.Fatalf("gob Decode into the old layout: %v", err)
+	}
+	for _, l := range legacy {
+		frags = append(frags, &Frag{Len: int64(l.Len), M: l.M, A: l.A, Enc: l.Enc})
+	}
+	want := "fragments stored by gob before Frag had GobEncode"
+	if zot, err := Reconstruct(frags); err != nil || string(zot) != want {
+		t.Errorf("Reconstruct: want %q got %q, %v", want, zot, err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data := []byte("fragments to paste into an issue report")
+	frags, err := Encode(data, 3, 4)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	frags[3].Enc[0] = int(MaxVal) // needs 32-bit Enc words
+	for i, f := range frags {
+		buf, err := json.Marshal(f)
+		if err != nil {
+			t.Fatalf("fragment %d: Marshal: %v", i, err)
+		}
+		var g Frag
+		if err := json.Unmarshal(buf, &g); err != nil {
+			t.Fatalf("fragment %d: Unmarshal: %v", i, err)
+		}
+		if !reflect.DeepEqual(f, &g) {
+			t.Errorf("fragment %d: want %#v got %#v", i, f, &g)
+		}
+	}
+	f := *frags[0]
+	f.Len += int64(2 * f.M) // one column too many for Enc
+	buf, _ := json.Marshal(&f)
+	var g Frag
+	if err := json.Unmarshal(buf, &g); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("inconsistent Len: want %v got %v", ErrBadEncoding, err)
+	}
+	// 4*M overflows to the length of an empty A
+	if err := json.Unmarshal([]byte(`{"Len":0,"M":4611686018427387904,"A":"","Enc":""}`), &g); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("huge M: want %v got %v", ErrBadEncoding, err)
+	}
+	// 2*Words and 4*Words overflow to the length of an empty Enc
+	if err := json.Unmarshal([]byte(`{"Len":2,"M":1,"A":"AQAAAA==","Enc":"","Words":4611686018427387904}`), &g); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("huge Words: want %v got %v", ErrBadEncoding, err)
+	}
+}
+
+func TestLargeLen(t *testing.T) {
+	f := &Frag{Len: 1 << 40, M: 1, A: []Field{1}, Enc: []int{}}
+	buf, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	// Len survives the encoding intact, so the complaint is that Enc is too short for it
+	var g Frag
+	if err := g.UnmarshalBinary(buf); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("UnmarshalBinary: want %v got %v", ErrBadEncoding, err)
+	}
+	f.Len = -1
+	if _, err := Reconstruct([]*Frag{f}); !errors.Is(err, ErrInvalidLen) {
+		t.Errorf("Reconstruct: want %v got %v", ErrInvalidLen, err)
+	}
+}
+
+func TestMaxLen(t *testing.T) {
+	defer func(v int64) { MaxLen = v }(MaxLen)
+	frags, err := Encode(make([]byte, 1000), 2, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	buf, err := frags[0].MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	js, err := json.Marshal(frags[0])
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	for _, max := range []int64{0, 1000, 999} {
+		MaxLen = max
+		var want error
+		if max == 999 {
+			want = ErrTooLarge
+		}
+		var g Frag
+		if err := g.UnmarshalBinary(buf); !errors.Is(err, want) {
+			t.Errorf("MaxLen %d: UnmarshalBinary: want %v got %v", max, want, err)
+		}
+		if _, err := g.ReadFrom(bytes.NewReader(buf)); !errors.Is(err, want) {
+			t.Errorf("MaxLen %d: ReadFrom: want %v got %v", max, want, err)
+		}
+		if err := json.Unmarshal(js, &g); !errors.Is(err, want) {
+			t.Errorf("MaxLen %d: UnmarshalJSON: want %v got %v", max, want, err)
+		}
+	}
+
+	MaxLen = 0
+
+	// a header claiming a 4 GB body must not be believed before the body arrives
+	huge := slices.Clone(buf[0:binHeader])
+	huge[binHeader-1] = 0xFF
+	var g Frag
+	if _, err := g.ReadFrom(bytes.NewReader(huge)); err != io.ErrUnexpectedEOF {
+		t.Errorf("huge header: want %v got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	// an Enc length inconsistent with Len and M is rejected
+	bad := frags[0].Clone()
+	bad.Enc = append(bad.Enc, 1)
+	buf, err = bad.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	if err := g.UnmarshalBinary(buf); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("inconsistent Enc length: want %v got %v", ErrBadEncoding, err)
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	data := []byte("one fragment after another down the pipe")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var buf bytes.Buffer
+	var total int64
+	for _, f := range frags {
+		n, err := f.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("WriteTo: %v", err)
+		}
+		total += n
+	}
+	if total != int64(buf.Len()) {
+		t.Errorf("WriteTo: counted %d bytes, wrote %d", total, buf.Len())
+	}
+	enc := buf.Bytes()
+	r := iotest.OneByteReader(bytes.NewReader(enc))
+	for i, f := range frags {
+		var g Frag
+		n, err := g.ReadFrom(r)
+		if err != nil {
+			t.Fatalf("fragment %d: ReadFrom: %v", i, err)
+		}
+		total -= n
+		if !reflect.DeepEqual(f, &g) {
+			t.Errorf("fragment %d: want %#v got %#v", i, f, &g)
+		}
+	}
+	if total != 0 {
+		t.Errorf("ReadFrom: byte counts disagree by %d", total)
+	}
+	var g Frag
+	if _, err := g.ReadFrom(r); err != io.EOF {
+		t.Errorf("ReadFrom at end: want %v got %v", io.EOF, err)
+	}
+	for _, n := range []int{3, binHeader + 5, len(enc) / len(frags) / 2} {
+		if _, err := g.ReadFrom(bytes.NewReader(enc[0:n])); err != io.ErrUnexpectedEOF {
+			t.Errorf("ReadFrom truncated to %d: want %v got %v", n, io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	for _, size := range []int{0, 1, 100, 100000} {
+		data := make([]byte, size)
+		rand.Read(data)
+		frags, err := Encode(data, 3, 200)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		total := 0
+		wide := false
+		for i, f := range frags {
+			wide = wide || slices.Contains(f.Enc, int(MaxVal))
+			buf, err := f.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary: %v", err)
+			}
+			if f.Size() != len(buf) {
+				t.Errorf("size %d fragment %d: Size %d, encoding %d", size, i, f.Size(), len(buf))
+			}
+			total += len(buf)
+		}
+		if o := Overhead(size, 3, 200); o != total && !wide {
+			t.Errorf("size %d: Overhead %d, total %d", size, o, total)
+		}
+	}
+	f := &Frag{Len: 300, M: 1, A: []Field{1}, Enc: []int{int(MaxVal)}, Blocks: []int64{100, 200}}
+	if buf, _ := f.MarshalBinary(); f.Size() != len(buf) {
+		t.Errorf("wide fragment with Blocks: Size %d, encoding %d", f.Size(), len(buf))
+	}
+}
+
+func FuzzUnmarshalFrag(f *testing.F) {
+	frags, err := Encode([]byte("fuzz me"), 2, 3)
+	if err != nil {
+		f.Fatalf("Encode: %v", err)
+	}
+	e := NewEncoder(2, rand.NewSource(1))
+	e.Append([]byte("block one"))
+	e.Append([]byte("two"))
+	frags = append(frags, e.Finish()...)
+	for _, fr := range frags {
+		b, err := fr.MarshalBinary()
+		if err != nil {
+			f.Fatalf("MarshalBinary: %v", err)
+		}
+		f.Add(b)
+		js, err := fr.MarshalJSON()
+		if err != nil {
+			f.Fatalf("MarshalJSON: %v", err)
+		}
+		f.Add(js)
+	}
+	f.Add([]byte{})
+	f.Add([]byte(binMagic))
+	f.Add([]byte(`{"Len":0,"M":4611686018427387904,"A":"","Enc":""}`))
+	f.Add([]byte(`{"Len":2,"M":1,"A":"AQAAAA==","Enc":"","Words":4611686018427387904}`))
+	// Len near math.MaxInt64 once overflowed enclen, and with Pad, the slicing of the padding
+	f.Add([]byte(`{"Len":9223372036854775807,"M":1,"A":"AQAAAA==","Enc":"AAA=","Words":1}`))
+	huge, err := (&Frag{Len: math.MaxInt64, M: 1, A: []Field{1}, Enc: []int{0}, Pad: 1}).MarshalBinary()
+	if err != nil {
+		f.Fatalf("MarshalBinary: %v", err)
+	}
+	f.Add(huge)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		check := func(what string, g *Frag) {
+			if err := g.Valid(); err != nil {
+				t.Fatalf("%s accepted an invalid fragment: %v", what, err)
+			}
+			eb, err := g.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%s: MarshalBinary: %v", what, err)
+			}
+			var h Frag
+			if err := h.UnmarshalBinary(eb); err != nil || !h.Equal(g) {
+				t.Fatalf("%s: round trip failed: %v", what, err)
+			}
+		}
+		var g Frag
+		if err := g.UnmarshalBinary(b); err == nil {
+			check("UnmarshalBinary", &g)
+		}
+		var j Frag
+		if err := j.UnmarshalJSON(b); err == nil {
+			check("UnmarshalJSON", &j)
+		}
+		if g, err := ParseArmor(string(b)); err == nil {
+			check("ParseArmor", g)
+		}
+		// armour b properly, so the fuzzer reaches the decoding behind the checksum
+		s := pem.EncodeToMemory(&pem.Block{
+			Type:    armorType,
+			Headers: map[string]string{"Checksum": fmt.Sprintf("%08x", crc32.ChecksumIEEE(b))},
+			Bytes:   b,
+		})
+		if g, err := ParseArmor(string(s)); err == nil {
+			check("ParseArmor", g)
+		}
+	})
+}
+
+func TestSetIDEncoding(t *testing.T) {
+	frags, err := Encode([]byte("identified"), 2, 3)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	f := frags[0]
+	b, err := f.MarshalBinary()
+	if err != nil || len(b) != f.Size() {
+		t.Fatalf("MarshalBinary: %d bytes, Size %d, %v", len(b), f.Size(), err)
+	}
+	var g Frag
+	if err := g.UnmarshalBinary(b); err != nil || g.SetID != f.SetID || !g.Verify() {
+		t.Errorf("binary: SetID %x want %x (%v)", g.SetID, f.SetID, err)
+	}
+	js, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var h Frag
+	if err := json.Unmarshal(js, &h); err != nil || h.SetID != f.SetID || !h.Verify() {
+		t.Errorf("JSON: SetID %x want %x (%v)", h.SetID, f.SetID, err)
+	}
+	// without a SetID, the encoding and CRC are as before
+	f.SetID = 0
+	old := f.checksum()
+	if b0, _ := f.MarshalBinary(); len(b0) != len(b)-8 || b0[binHeader]&encSetID != 0 {
+		t.Errorf("encoding without SetID: %d bytes, want %d", len(b0), len(b)-8)
+	}
+	f.CRC = old
+	f.member(1, g.SetID)
+	if f.CRC != f.checksum() {
+		t.Errorf("member: CRC %08x, checksum %08x", f.CRC, f.checksum())
+	}
+	// nor does padding upset member
+	p, err := FragmentPadded([]byte("identified"), 2, 9)
+	if err != nil {
+		t.Fatalf("FragmentPadded: %v", err)
+	}
+	p.member(2, g.SetID)
+	if !p.Verify() {
+		t.Errorf("member of padded fragment: CRC %08x, checksum %08x", p.CRC, p.checksum())
+	}
+}
@@ -0,0 +1,341 @@
+package ida
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	ErrCauchyNodes = errors.New("Cauchy node values must be distinct field elements")
+	ErrNotCauchy   = errors.New("not a Cauchy matrix")
+	ErrInvalidID   = errors.New("Vandermonde node id must be in the interval [1, MaxVal]")
+	ErrDimension   = errors.New("matrix dimensions do not match")
+)
+
+// CauchyMatrix returns the m×m Cauchy matrix with elements 1/(xs[i]-ys[j]).
+// Every square submatrix of a Cauchy matrix is non-singular, so any subset of its rows
+// (or any m of them together with rows of the identity matrix) can serve as an encoding matrix.
+// It returns an error unless xs and ys have length m, and all the node values in xs and ys
+// are distinct elements of the field.
+func CauchyMatrix(m int, xs, ys []Field) (Matrix, error) {
+	if len(xs) != m || len(ys) != m {
+		return nil, ErrNonSquare
+	}
+	seen := make(map[Field]bool, 2*m)
+	for _, v := range append(xs[0:m:m], ys...) {
+		if v > MaxVal || seen[v] {
+			return nil, ErrCauchyNodes
+		}
+		seen[v] = true
+	}
+	c := NewMatrix(m)
+	for i := range c {
+		c[i] = make([]Field, m)
+		for j := range c[i] {
+			c[i][j] = Field(1).div(xs[i].sub(ys[j]))
+		}
+	}
+	return c, nil
+}
+
+// InvertCauchy inverts a Cauchy matrix, such as one returned by CauchyMatrix,
+// in O(m^2) operations using the closed form for the inverse, leaving the original matrix untouched.
+// The node values are recovered from the matrix itself,
+// and InvertCauchy returns an error if a is not a Cauchy matrix.
+func (a Matrix) InvertCauchy() (Matrix, error) {
+	m := len(a)
+	for _, r := range a {
+		if len(r) != m {
+			return nil, ErrNonSquare
+		}
+	}
+	if m == 0 {
+		return Matrix{}, nil
+	}
+	// the matrix is unchanged by adding the same value to all nodes, so take ys[0] = 0
+	xs := make([]Field, m)
+	ys := make([]Field, m)
+	for i := range xs {
+		if a[i][0] == 0 {
+			return nil, ErrNotCauchy
+		}
+		xs[i] = Field(1).div(a[i][0])
+	}
+	for j := range ys {
+		if a[0][j] == 0 {
+			return nil, ErrNotCauchy
+		}
+		ys[j] = xs[0].sub(Field(1).div(a[0][j]))
+	}
+	for i := range a {
+		for j := range a[i] {
+			if a[i][j] == 0 || xs[i].sub(ys[j]) != Field(1).div(a[i][j]) {
+				return nil, ErrNotCauchy
+			}
+		}
+	}
+	// inv[i][j] = px[j]*py[i] / (xs[j]-ys[i]), where
+	// px[j] = ∏k (xs[j]-ys[k]) / ∏k≠j (xs[j]-xs[k]) and py[i] = ∏k (xs[k]-ys[i]) / ∏k≠i (ys[k]-ys[i])
+	px := make([]Field, m)
+	py := make([]Field, m)
+	for i := 0; i < m; i++ {
+		nx, dx := Field(1), Field(1)
+		ny, dy := Field(1), Field(1)
+		for k := 0; k < m; k++ {
+			nx = nx.mul(xs[i].sub(ys[k]))
+			ny = ny.mul(xs[k].sub(ys[i]))
+			if k != i {
+				dx = dx.mul(xs[i].sub(xs[k]))
+				dy = dy.mul(ys[k].sub(ys[i]))
+			}
+		}
+		if dx == 0 || dy == 0 {
+			return nil, ErrCauchyNodes
+		}
+		px[i] = nx.div(dx)
+		py[i] = ny.div(dy)
+	}
+	inv := NewMatrix(m)
+	for i := range inv {
+		inv[i] = make([]Field, m)
+		for j := range inv[i] {
+			inv[i][j] = px[j].mul(py[i]).div(xs[j].sub(ys[i]))
+		}
+	}
+	return inv, nil
+}
+
+// VandermondeRow returns the encoding row of length m for node id, with elements id^j for j in [0, m).
+// The rows for any m distinct ids form a Vandermonde matrix, which is invertible,
+// so fragments made with such rows can always be reconstructed from any m of them.
+// It returns an error if m < 1 or id is not in the interval [1, MaxVal].
+func VandermondeRow(id Field, m int) ([]Field, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	if id == 0 || id > MaxVal {
+		return nil, ErrInvalidID
+	}
+	a := make([]Field, m)
+	v := Field(1)
+	for j := range a {
+		a[j] = v
+		v = v.mul(id)
+	}
+	return a, nil
+}
+
+// basis accumulates linearly independent rows, in reduced form.
+// Each row has a pivot column where its value is 1, and rows added later have zero in that column.
+type basis struct {
+	rows  [][]Field
+	pivot []int
+}
+
+// add reduces a copy of row against the basis, adding it and returning true
+// if it is independent of the rows already there, and returning false otherwise.
+func (b *basis) add(row []Field) bool {
+	r := make([]Field, len(row))
+	copy(r, row)
+	for i, br := range b.rows {
+		if x := r[b.pivot[i]]; x != 0 {
+			for c := range r {
+				r[c] = r[c].sub(x.mul(br[c]))
+			}
+		}
+	}
+	for c, x := range r {
+		if x != 0 {
+			for c1 := c; c1 < len(r); c1++ {
+				r[c1] = r[c1].div(x)
+			}
+			b.rows = append(b.rows, r)
+			b.pivot = append(b.pivot, c)
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityMatrix returns a new m×m identity matrix.
+func IdentityMatrix(m int) Matrix {
+	a := NewMatrix(m)
+	for i := range a {
+		a[i] = make([]Field, m)
+		a[i][i] = 1
+	}
+	return a
+}
+
+// Copy returns a new matrix with the same elements as a, sharing no storage with it.
+func (a Matrix) Copy() Matrix {
+	c := make(Matrix, len(a))
+	for i, row := range a {
+		c[i] = slices.Clone(row)
+	}
+	return c
+}
+
+// Transpose returns a new matrix that is the transpose of a, which must be rectangular.
+func (a Matrix) Transpose() Matrix {
+	if len(a) == 0 {
+		return Matrix{}
+	}
+	t := make(Matrix, len(a[0]))
+	for j := range t {
+		t[j] = make([]Field, len(a))
+		for i, row := range a {
+			t[j][i] = row[j]
+		}
+	}
+	return t
+}
+
+// Rank returns the rank of a, the number of its linearly independent rows, leaving a untouched.
+// An m×m encoding matrix can be inverted, and its fragments decoded, only if its rank is m.
+func (a Matrix) Rank() int {
+	var b basis
+	for _, row := range a {
+		b.add(row)
+	}
+	return len(b.rows)
+}
+
+// IsInvertible returns true if a is square and nonsingular, leaving a untouched.
+// It reduces the rows in turn, stopping at the first that is dependent on those before it,
+// so it is cheaper than Invert, and cheaper still for a singular matrix.
+//
+// There are no questions of conditioning in a finite field: a matrix is either invertible or not.
+// A random m×m matrix over Z(Prime) is singular with probability
+// 1-(1-1/p)(1-1/p²)...(1-1/pᵐ), which is a little more than 1/p, about 1 in 65536, for any m,
+// so a set of random encoding rows occasionally cannot be decoded, and should be drawn again.
+func (a Matrix) IsInvertible() bool {
+	var b basis
+	for _, row := range a {
+		if len(row) != len(a) || !b.add(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// Determinant returns the determinant of a, leaving a untouched, or ErrNonSquare if a is not square.
+// It reduces a copy of a to upper triangular form by Gaussian elimination with row swaps,
+// and is zero exactly when a is singular.
+func (a Matrix) Determinant() (Field, error) {
+	m := len(a)
+	w := make(Matrix, m)
+	for r := range a {
+		if len(a[r]) != m {
+			return zero, ErrNonSquare
+		}
+		w[r] = make([]Field, m)
+		copy(w[r], a[r])
+	}
+	det := Field(1)
+	for r := 0; r < m; r++ {
+		for p := r + 1; w[r][r] == 0 && p < m; p++ {
+			if w[p][r] != 0 {
+				w[r], w[p] = w[p], w[r]
+				det = zero.sub(det) // a swap changes the sign
+			}
+		}
+		x := w[r][r]
+		if x == 0 {
+			return zero, nil
+		}
+		det = det.mul(x)
+		inv := Field(1).div(x)
+		for r1 := r + 1; r1 < m; r1++ {
+			if y := w[r1][r].mul(inv); y != 0 {
+				for c := r; c < m; c++ {
+					w[r1][c] = w[r1][c].sub(y.mul(w[r][c]))
+				}
+			}
+		}
+	}
+	return det, nil
+}
+
+// Mul returns the product a·b in the field, leaving both untouched.
+// It returns ErrDimension unless each row of a has as many elements as b has rows,
+// and the rows of b all have the same length.
+func (a Matrix) Mul(b Matrix) (Matrix, error) {
+	n := len(b)
+	p := 0
+	if n > 0 {
+		p = len(b[0])
+	}
+	for _, row := range b {
+		if len(row) != p {
+			return nil, ErrDimension
+		}
+	}
+	out := make(Matrix, len(a))
+	for i, row := range a {
+		if len(row) != n {
+			return nil, ErrDimension
+		}
+		out[i] = make([]Field, p)
+		for k, x := range row {
+			if x != 0 {
+				for j, y := range b[k] {
+					out[i][j] = out[i][j].add(x.mul(y))
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// Solve returns the matrix x such that a·x = rhs, where a is square and rhs has the same number of rows,
+// leaving both a and rhs untouched.
+// It does a single Gauss-Jordan elimination with the columns of rhs as the right-hand sides,
+// which saves forming the inverse of a when the solution for only one rhs is wanted.
+// Solve returns an error if a is singular or non-square, or rhs has the wrong number of rows.
+func (a Matrix) Solve(rhs Matrix) (Matrix, error) {
+	m := len(a)
+	if len(rhs) != m {
+		return nil, ErrNonSquare
+	}
+	w := make(Matrix, m)
+	x := make(Matrix, m)
+	for r := 0; r < m; r++ {
+		if len(a[r]) != m || len(rhs[r]) != len(rhs[0]) {
+			return nil, ErrNonSquare
+		}
+		w[r] = make([]Field, m)
+		copy(w[r], a[r])
+		x[r] = make([]Field, len(rhs[r]))
+		copy(x[r], rhs[r])
+	}
+	for r := 0; r < m; r++ {
+		for p := r + 1; w[r][r] == 0 && p < m; p++ {
+			if w[p][r] != 0 {
+				w[r], w[p] = w[p], w[r]
+				x[r], x[p] = x[p], x[r]
+			}
+		}
+		if w[r][r] == 0 {
+			return nil, a.singular()
+		}
+		inv := Field(1).div(w[r][r])
+		for c := range w[r] {
+			w[r][c] = w[r][c].mul(inv)
+		}
+		for c := range x[r] {
+			x[r][c] = x[r][c].mul(inv)
+		}
+		for r1 := 0; r1 < m; r1++ {
+			if y := w[r1][r]; r1 != r && y != 0 {
+				for c := range w[r1] {
+					w[r1][c] = w[r1][c].sub(y.mul(w[r][c]))
+				}
+				for c := range x[r1] {
+					x[r1][c] = x[r1][c].sub(y.mul(x[r][c]))
+				}
+			}
+		}
+	}
+	return x, nil
+}
@@ -0,0 +1,305 @@
+package ida
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestCauchy(t *testing.T) {
+	for _, m := range []int{1, 2, 3, 8, 33} {
+		xs := make([]Field, m)
+		ys := make([]Field, m)
+		for i := range xs {
+			xs[i] = Field(3*i + 7)
+			ys[i] = MaxVal - Field(5*i)
+		}
+		c, err := CauchyMatrix(m, xs, ys)
+		if err != nil {
+			t.Fatalf("CauchyMatrix(%d): %v", m, err)
+		}
+		want, err := c.Invert()
+		if err != nil {
+			t.Fatalf("Invert(%d): %v", m, err)
+		}
+		got, err := c.InvertCauchy()
+		if err != nil {
+			t.Fatalf("InvertCauchy(%d): %v", m, err)
+		}
+		for i := range want {
+			if !slices.Equal(want[i], got[i]) {
+				t.Errorf("InvertCauchy(%d): want\n%vgot\n%v", m, want, got)
+				break
+			}
+		}
+	}
+	if _, err := CauchyMatrix(2, []Field{1, 2}, []Field{3, 1}); err != ErrCauchyNodes {
+		t.Errorf("CauchyMatrix with x=y: want %v got %v", ErrCauchyNodes, err)
+	}
+	if _, err := CauchyMatrix(2, []Field{1, 1}, []Field{3, 4}); err != ErrCauchyNodes {
+		t.Errorf("CauchyMatrix with repeated x: want %v got %v", ErrCauchyNodes, err)
+	}
+	if _, err := (Matrix{{1, 2}, {3, 4}}).InvertCauchy(); err != ErrNotCauchy {
+		t.Errorf("InvertCauchy of non-Cauchy matrix: want %v got %v", ErrNotCauchy, err)
+	}
+}
+
+func TestVandermonde(t *testing.T) {
+	a, err := VandermondeRow(3, 4)
+	if err != nil {
+		t.Fatalf("VandermondeRow: %v", err)
+	}
+	if want := []Field{1, 3, 9, 27}; !slices.Equal(a, want) {
+		t.Errorf("VandermondeRow(3, 4): want %v got %v", want, a)
+	}
+	for _, id := range []Field{0, MaxVal + 1} {
+		if _, err := VandermondeRow(id, 4); err != ErrInvalidID {
+			t.Errorf("VandermondeRow(%d, 4): want %v got %v", id, ErrInvalidID, err)
+		}
+	}
+	data := []byte("any m distinct node ids will do")
+	const m = 5
+	var frags []*Frag
+	for _, id := range []Field{MaxVal, 1, 2, 1000, 65535, 77} {
+		f, err := FragmentID(data, id, m)
+		if err != nil {
+			t.Fatalf("FragmentID(%d): %v", id, err)
+		}
+		frags = append(frags, f)
+	}
+	for s := 0; s+m <= len(frags); s++ {
+		zot, err := Reconstruct(frags[s : s+m])
+		if err != nil {
+			t.Errorf("Reconstruct from %d: %v", s, err)
+			continue
+		}
+		if string(zot) != string(data) {
+			t.Errorf("Reconstruct from %d: want %q got %q", s, data, zot)
+		}
+	}
+}
+
+func TestSolve(t *testing.T) {
+	a := Matrix{{0, 1, 2}, {3, 4, 5}, {6, 7, 9}} // zero pivot first
+	rhs := Matrix{{1, 0}, {2, MaxVal}, {3, 17}}
+	x, err := a.Solve(rhs)
+	if err != nil {
+		t.Fatalf("Solve: %v", err)
+	}
+	for i := range a {
+		for k := range rhs[i] {
+			s := zero
+			for j := range a[i] {
+				s = s.add(a[i][j].mul(x[j][k]))
+			}
+			if s != rhs[i][k] {
+				t.Errorf("Solve: row %d column %d: want %d got %d", i, k, rhs[i][k], s)
+			}
+		}
+	}
+	if _, err := (Matrix{{1, 2}, {2, 4}}).Solve(Matrix{{1}, {1}}); !errors.Is(err, ErrZeroPivot) {
+		t.Errorf("Solve of singular matrix: want %v got %v", ErrZeroPivot, err)
+	}
+	if _, err := a.Solve(rhs[0:2]); err != ErrNonSquare {
+		t.Errorf("Solve with short rhs: want %v got %v", ErrNonSquare, err)
+	}
+}
+
+func TestRank(t *testing.T) {
+	for _, c := range []struct {
+		a    Matrix
+		rank int
+	}{
+		{Matrix{}, 0},
+		{Matrix{{0, 0}, {0, 0}}, 0},
+		{Matrix{{1, 2}, {2, 4}}, 1},
+		{Matrix{{1, 2}, {2, 5}}, 2},
+		{Matrix{{0, 1, 2}, {3, 4, 5}, {6, 7, 9}}, 3},
+		{Matrix{{1, 2, 3}, {4, 5, 6}, {5, 7, 9}}, 2},
+		{Matrix{{1, 2, 3}, {1, 2, 3}, {MaxVal, MaxVal - 1, MaxVal - 2}}, 1},
+		{Matrix{{1, 2, 3}, {4, 5, 6}}, 2},
+	} {
+		before := c.a.String()
+		if r := c.a.Rank(); r != c.rank {
+			t.Errorf("Rank of\n%vwant %d got %d", c.a, c.rank, r)
+		}
+		if c.a.String() != before {
+			t.Errorf("Rank changed its matrix")
+		}
+	}
+}
+
+func TestIsInvertible(t *testing.T) {
+	for _, c := range []struct {
+		a   Matrix
+		inv bool
+	}{
+		{Matrix{}, true},
+		{Matrix{{0}}, false},
+		{Matrix{{1, 2}, {2, 4}}, false},
+		{Matrix{{1, 2}, {2, 5}}, true},
+		{Matrix{{0, 1, 2}, {3, 4, 5}, {6, 7, 9}}, true},
+		{Matrix{{2, 0, 1}, {1, 3, 2}, {1, 1, 1}}, false},
+		{Matrix{{1, 2, 3}, {4, 5, 6}}, false},
+		{Matrix{{1, 2}, {3, 4}, {5, 6}}, false},
+	} {
+		before := c.a.String()
+		if inv := c.a.IsInvertible(); inv != c.inv {
+			t.Errorf("IsInvertible of\n%vwant %v got %v", c.a, c.inv, inv)
+		}
+		if c.a.String() != before {
+			t.Errorf("IsInvertible changed its matrix")
+		}
+		if _, err := c.a.Invert(); (err == nil) != c.inv {
+			t.Errorf("IsInvertible of\n%vdisagrees with Invert: %v", c.a, err)
+		}
+	}
+}
+
+func TestDeterminant(t *testing.T) {
+	for _, c := range []struct {
+		a   Matrix
+		det Field
+	}{
+		{Matrix{}, 1},
+		{Matrix{{7}}, 7},
+		{Matrix{{1, 2}, {3, 4}}, Prime - 2},
+		{Matrix{{0, 1}, {1, 0}}, MaxVal},
+		{Matrix{{0, 1, 2}, {3, 4, 5}, {6, 7, 9}}, Prime - 3},
+		{Matrix{{2, 0, 1}, {1, 3, 2}, {1, 1, 1}}, 0},
+		{Matrix{{MaxVal, 0}, {0, MaxVal}}, 1},
+	} {
+		det, err := c.a.Determinant()
+		if err != nil || det != c.det {
+			t.Errorf("Determinant of\n%vwant %d got %d, %v", c.a, c.det, det, err)
+		}
+	}
+	// the Vandermonde determinant is the product of the differences of the nodes
+	ids := []Field{3, 1, 4, 15, 9, 26}
+	v := NewMatrix(len(ids))
+	want := Field(1)
+	for i, id := range ids {
+		v[i], _ = VandermondeRow(id, len(ids))
+		for _, id0 := range ids[0:i] {
+			want = want.mul(id.sub(id0))
+		}
+	}
+	if det, err := v.Determinant(); err != nil || det != want {
+		t.Errorf("Vandermonde determinant: want %d got %d, %v", want, det, err)
+	}
+	if _, err := (Matrix{{1, 2}}).Determinant(); err != ErrNonSquare {
+		t.Errorf("Determinant of non-square matrix: want %v got %v", ErrNonSquare, err)
+	}
+}
+
+func TestMul(t *testing.T) {
+	a := Matrix{{1, 2, 3}, {4, 5, 6}}
+	b := Matrix{{1, 0}, {0, 1}, {MaxVal, 2}}
+	want := Matrix{{Prime - 2, 8}, {Prime - 2, 17}}
+	got, err := a.Mul(b)
+	if err != nil || got.String() != want.String() {
+		t.Errorf("Mul: want\n%vgot\n%v%v", want, got, err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for _, m := range []int{1, 2, 5, 17} {
+		a := NewMatrix(m)
+		for i := range a {
+			a[i] = randomVec(rnd, m)
+		}
+		ainv, err := a.Invert()
+		if err != nil {
+			continue // singular by chance
+		}
+		for _, p := range []Matrix{must(a.Mul(ainv)), must(ainv.Mul(a))} {
+			for i := range p {
+				for j := range p[i] {
+					if want := Field(b2i(i == j)); p[i][j] != want {
+						t.Fatalf("m=%d: a·a⁻¹ not the identity:\n%v", m, p)
+					}
+				}
+			}
+		}
+	}
+	if _, err := a.Mul(a); err != ErrDimension {
+		t.Errorf("Mul 2×3 by 2×3: want %v got %v", ErrDimension, err)
+	}
+	if _, err := a.Mul(Matrix{{1}, {2, 3}, {4}}); err != ErrDimension {
+		t.Errorf("Mul by ragged matrix: want %v got %v", ErrDimension, err)
+	}
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func must(a Matrix, err error) Matrix {
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func TestCopy(t *testing.T) {
+	a := Matrix{{1, 2, 3}, {4, 5, 6}}
+	c := a.Copy()
+	if c.String() != a.String() {
+		t.Errorf("Copy: want\n%vgot\n%v", a, c)
+	}
+	c[1][2] = 7
+	if a[1][2] != 6 {
+		t.Errorf("Copy shares storage with the original")
+	}
+	if c := (Matrix{}).Copy(); len(c) != 0 {
+		t.Errorf("Copy of empty matrix: got %v", c)
+	}
+}
+
+func TestInvertUntouched(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, m := range []int{1, 3, 8} {
+		a := NewMatrix(m)
+		for i := range a {
+			a[i] = randomVec(rnd, m)
+		}
+		a[0], a[m-1] = IdentityMatrix(m)[m-1], a[0] // a zero pivot forces a swap
+		before := a.Copy()
+		rows := slices.Clone(a)
+		if _, err := a.Invert(); err != nil {
+			t.Fatalf("Invert(%d): %v", m, err)
+		}
+		if a.String() != before.String() {
+			t.Errorf("Invert(%d) changed its matrix: want\n%vgot\n%v", m, before, a)
+		}
+		for i := range a {
+			if &a[i][0] != &rows[i][0] {
+				t.Errorf("Invert(%d) replaced row %d", m, i)
+			}
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	a := Matrix{{1, 2, 3}, {4, 5, 6}}
+	want := Matrix{{1, 4}, {2, 5}, {3, 6}}
+	if at := a.Transpose(); at.String() != want.String() {
+		t.Errorf("Transpose: want\n%vgot\n%v", want, at)
+	}
+	if att := a.Transpose().Transpose(); att.String() != a.String() {
+		t.Errorf("Transpose twice: want\n%vgot\n%v", a, att)
+	}
+	if id := IdentityMatrix(3); id.String() != IdentityMatrix(3).Transpose().String() {
+		t.Errorf("identity not symmetric:\n%v", id)
+	}
+	for _, p := range []Matrix{must(IdentityMatrix(2).Mul(a)), must(a.Mul(IdentityMatrix(3)))} {
+		if p.String() != a.String() {
+			t.Errorf("identity product: want\n%vgot\n%v", a, p)
+		}
+	}
+	if n := len(IdentityMatrix(0)); n != 0 {
+		t.Errorf("IdentityMatrix(0) has %d rows", n)
+	}
+}
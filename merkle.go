@@ -0,0 +1,104 @@
+package ida
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// A Merkle tree over a set of fragments commits to all of them with one hash, its root,
+// and lets any one fragment be shown to belong to the set by a proof of log₂ n hashes,
+// without the others. The leaves are the SHA-256 hashes of the binary encodings of the fragments,
+// in order, padded with empty leaves to a power of two; each interior node is the hash of its two children.
+// Leaves and interior nodes are hashed with different prefixes, so that one cannot pass for the other.
+
+var ErrNilFragment = errors.New("nil fragment")
+
+// BuildMerkle returns the root of the Merkle tree over frags, and for each fragment,
+// the proof that it is in the tree: the hashes of the siblings of the nodes on its path to the root,
+// from the bottom up, concatenated.
+// It returns ErrTooFewFragments if frags is empty, and a [*FragmentError] if a fragment is nil or cannot be encoded.
+func BuildMerkle(frags []*Frag) (root []byte, proofs [][]byte, err error) {
+	if len(frags) == 0 {
+		return nil, nil, ErrTooFewFragments
+	}
+	width := 1
+	for width < len(frags) {
+		width *= 2
+	}
+	level := make([][]byte, width)
+	for i := range level {
+		if i >= len(frags) {
+			level[i] = merkleLeaf(nil)
+			continue
+		}
+		if frags[i] == nil {
+			return nil, nil, &FragmentError{i, ErrNilFragment}
+		}
+		buf, err := frags[i].MarshalBinary()
+		if err != nil {
+			return nil, nil, &FragmentError{i, err}
+		}
+		level[i] = merkleLeaf(buf)
+	}
+	proofs = make([][]byte, len(frags))
+	for ; len(level) > 1; level = merkleUp(level) {
+		for i := range proofs {
+			proofs[i] = append(proofs[i], level[(i>>merkleDepth(proofs[i]))^1]...)
+		}
+	}
+	return level[0], proofs, nil
+}
+
+// VerifyMerkleProof returns true if proof, as returned by BuildMerkle, shows that f is fragment i
+// of the set with Merkle tree root.
+func VerifyMerkleProof(root []byte, f *Frag, i int, proof []byte) bool {
+	if f == nil || i < 0 || len(proof)%sha256.Size != 0 || i>>merkleDepth(proof) != 0 {
+		return false
+	}
+	buf, err := f.MarshalBinary()
+	if err != nil {
+		return false
+	}
+	h := merkleLeaf(buf)
+	for ; len(proof) > 0; proof, i = proof[sha256.Size:], i>>1 {
+		sib := proof[0:sha256.Size]
+		if i&1 == 0 {
+			h = merkleNode(h, sib)
+		} else {
+			h = merkleNode(sib, h)
+		}
+	}
+	return bytes.Equal(h, root)
+}
+
+// merkleDepth returns the number of hashes in proof.
+func merkleDepth(proof []byte) int {
+	return len(proof) / sha256.Size
+}
+
+// merkleUp returns the level of the tree above level.
+func merkleUp(level [][]byte) [][]byte {
+	up := make([][]byte, len(level)/2)
+	for i := range up {
+		up[i] = merkleNode(level[2*i], level[2*i+1])
+	}
+	return up
+}
+
+// merkleLeaf returns the hash of a leaf with the given contents.
+func merkleLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// merkleNode returns the hash of an interior node with the given children.
+func merkleNode(l, r []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(l)
+	h.Write(r)
+	return h.Sum(nil)
+}
@@ -0,0 +1,61 @@
+package ida
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMerkle(t *testing.T) {
+	data := []byte("committed to, one fragment at a time")
+	for _, n := range []int{1, 2, 3, 5, 8} {
+		frags, err := Encode(data, 1, n)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		root, proofs, err := BuildMerkle(frags)
+		if err != nil {
+			t.Fatalf("BuildMerkle(%d): %v", n, err)
+		}
+		if len(proofs) != n {
+			t.Fatalf("BuildMerkle(%d): %d proofs", n, len(proofs))
+		}
+		for i, f := range frags {
+			if !VerifyMerkleProof(root, f, i, proofs[i]) {
+				t.Errorf("n=%d: proof of fragment %d fails", n, i)
+			}
+			if n > 1 && VerifyMerkleProof(root, f, (i+1)%n, proofs[i]) {
+				t.Errorf("n=%d: proof of fragment %d passes at %d", n, i, (i+1)%n)
+			}
+			if n > 1 && VerifyMerkleProof(root, f, i, proofs[(i+1)%n]) {
+				t.Errorf("n=%d: fragment %d passes with another's proof", n, i)
+			}
+			g := f.Clone()
+			g.Enc[0] ^= 1
+			if VerifyMerkleProof(root, g, i, proofs[i]) {
+				t.Errorf("n=%d: altered fragment %d passes", n, i)
+			}
+		}
+		// the same set, in the same order, gives the same root; a different set does not
+		again, _, err := BuildMerkle(frags)
+		if err != nil || !bytes.Equal(again, root) {
+			t.Errorf("BuildMerkle(%d) twice: roots %x and %x, %v", n, root, again, err)
+		}
+		other, _ := Encode(data, 1, n)
+		if r, _, _ := BuildMerkle(other); bytes.Equal(r, root) {
+			t.Errorf("BuildMerkle(%d): another set has the same root", n)
+		}
+	}
+	if _, _, err := BuildMerkle(nil); err != ErrTooFewFragments {
+		t.Errorf("BuildMerkle(nil): want %v got %v", ErrTooFewFragments, err)
+	}
+	frags, _ := Encode(data, 2, 3)
+	frags[1] = nil
+	var fe *FragmentError
+	if _, _, err := BuildMerkle(frags); !errors.As(err, &fe) || fe.Index != 1 || !errors.Is(err, ErrNilFragment) {
+		t.Errorf("BuildMerkle with nil: want fragment 1: %v got %v", ErrNilFragment, err)
+	}
+	if VerifyMerkleProof(nil, frags[0], 0, []byte{1, 2, 3}) {
+		t.Errorf("VerifyMerkleProof with a short proof: passes")
+	}
+}
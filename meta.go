@@ -0,0 +1,110 @@
+package ida
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// FragMeta holds the parameters of a set of fragments, without any encoded data,
+// so that an index of a dispersal store can record them cheaply,
+// and check the fragments it later fetches (see [ConsistentWith]).
+// It has a compact binary encoding; encoding/json needs no help, giving Digest in base64.
+type FragMeta struct {
+	M      int    // the minimum number of fragments for reconstruction
+	N      int    // the number of fragments made, or zero if not known
+	Len    int64  // the length of the data
+	SetID  uint64 // the SetID of the fragments, or zero if they have none
+	Digest []byte // the Digest of the data, or nil if there is none
+}
+
+// Meta returns the metadata of the set of fragments to which f belongs.
+// A Frag does not record how many fragments were made, so N is zero, for the caller to set.
+func (f *Frag) Meta() FragMeta {
+	return FragMeta{M: f.M, Len: f.Len, SetID: f.SetID, Digest: bytes.Clone(f.Digest)}
+}
+
+// Match returns true if f agrees with md: it has the same M, Len, SetID (unless md's is zero)
+// and Digest (unless md's is nil), and an Index no greater than N, if N and the Index are known.
+func (md FragMeta) Match(f *Frag) bool {
+	return f != nil && f.M == md.M && f.Len == md.Len &&
+		(md.SetID == 0 || f.SetID == md.SetID) &&
+		(md.Digest == nil || bytes.Equal(f.Digest, md.Digest)) &&
+		(md.N == 0 || f.Index <= md.N)
+}
+
+// ConsistentWith is like [Consistent], but first discards the fragments that do not Match md,
+// metadata from a trusted source, so that the majority of frags cannot outvote it.
+func ConsistentWith(frags []*Frag, md FragMeta) ([]*Frag, error) {
+	var match []*Frag
+	for _, f := range frags {
+		if md.Match(f) {
+			match = append(match, f)
+		}
+	}
+	if len(match) < md.M {
+		return nil, ErrTooFewFragments
+	}
+	return Consistent(match)
+}
+
+// The binary encoding of a FragMeta is the magic string "idm" and a version byte,
+// followed by M, N and Len as unsigned varints, SetID as a little-endian 64-bit value,
+// and the length of Digest as an unsigned varint, and Digest itself.
+
+const (
+	metaMagic   = "idm"
+	metaVersion = 1
+)
+
+// MarshalBinary returns the binary encoding of md.
+func (md FragMeta) MarshalBinary() ([]byte, error) {
+	if md.M < 0 || md.N < 0 || md.Len < 0 {
+		return nil, fmt.Errorf("%w: negative parameter", ErrBadEncoding)
+	}
+	buf := make([]byte, 0, len(metaMagic)+1+3*binary.MaxVarintLen64+8+1+len(md.Digest))
+	buf = append(buf, metaMagic...)
+	buf = append(buf, metaVersion)
+	buf = binary.AppendUvarint(buf, uint64(md.M))
+	buf = binary.AppendUvarint(buf, uint64(md.N))
+	buf = binary.AppendUvarint(buf, uint64(md.Len))
+	buf = binary.LittleEndian.AppendUint64(buf, md.SetID)
+	buf = binary.AppendUvarint(buf, uint64(len(md.Digest)))
+	buf = append(buf, md.Digest...)
+	return buf, nil
+}
+
+// UnmarshalBinary sets md to the FragMeta with the given binary encoding, as produced by MarshalBinary.
+// It returns an error, leaving md unchanged, if the encoding is truncated, has data left over,
+// or gives M < 1, N less than M but not zero, or a Len greater than MaxLen.
+func (md *FragMeta) UnmarshalBinary(data []byte) error {
+	if len(data) < len(metaMagic)+1 || string(data[0:len(metaMagic)]) != metaMagic {
+		return fmt.Errorf("%w: not fragment metadata", ErrBadEncoding)
+	}
+	if v := data[len(metaMagic)]; v != metaVersion {
+		return fmt.Errorf("%w: unknown version %d", ErrBadEncoding, v)
+	}
+	d := decbuf{b: data[len(metaMagic)+1:]}
+	m := d.int()
+	n := d.int()
+	dlen := d.int64()
+	id := d.uint64()
+	var dg []byte
+	if n := d.int(); n > 0 {
+		dg = bytes.Clone(d.bytes(n))
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.b) != 0 {
+		return fmt.Errorf("%w: %d bytes left over", ErrBadEncoding, len(d.b))
+	}
+	if MaxLen > 0 && dlen > MaxLen {
+		return fmt.Errorf("%w: %d", ErrTooLarge, dlen)
+	}
+	if m < 1 || n != 0 && n < m {
+		return fmt.Errorf("%w: inconsistent parameters", ErrBadEncoding)
+	}
+	*md = FragMeta{M: m, N: n, Len: dlen, SetID: id, Digest: dg}
+	return nil
+}
@@ -0,0 +1,89 @@
+package ida
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFragMeta(t *testing.T) {
+	data := []byte("what the index knows about an object")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	md := frags[2].Meta()
+	if md.M != 3 || md.N != 0 || md.Len != int64(len(data)) || md.SetID != frags[0].SetID || !bytes.Equal(md.Digest, frags[0].Digest) {
+		t.Errorf("Meta: got %+v", md)
+	}
+	md.N = len(frags)
+	for i, f := range frags {
+		if !md.Match(f) {
+			t.Errorf("Match: fragment %d does not match its own metadata", i)
+		}
+	}
+
+	buf, err := md.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var md1 FragMeta
+	if err := md1.UnmarshalBinary(buf); err != nil || !reflect.DeepEqual(md1, md) {
+		t.Errorf("binary round trip: want %+v got %+v, %v", md, md1, err)
+	}
+	js, err := json.Marshal(md)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var md2 FragMeta
+	if err := json.Unmarshal(js, &md2); err != nil || !reflect.DeepEqual(md2, md) {
+		t.Errorf("JSON round trip: want %+v got %+v, %v", md, md2, err)
+	}
+	for _, bad := range [][]byte{nil, buf[0:5], append(bytes.Clone(buf), 0), []byte("ida\x01")} {
+		if err := md1.UnmarshalBinary(bad); !errors.Is(err, ErrBadEncoding) {
+			t.Errorf("UnmarshalBinary(%q): want %v got %v", bad, ErrBadEncoding, err)
+		}
+	}
+	if buf, _ := (FragMeta{M: 3, N: 2}).MarshalBinary(); !errors.Is(md1.UnmarshalBinary(buf), ErrBadEncoding) {
+		t.Errorf("UnmarshalBinary with N < M: want %v", ErrBadEncoding)
+	}
+	if !reflect.DeepEqual(md1, md) {
+		t.Errorf("failed UnmarshalBinary changed its receiver")
+	}
+}
+
+func TestConsistentWith(t *testing.T) {
+	data := []byte("the index is trusted, the fragments less so")
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	md := frags[0].Meta()
+	md.N = 5
+	// a majority of fragments of another encoding would win a vote, but not against md
+	other, err := Encode([]byte("an impostor with the same length as the data"[0:len(data)]), 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	set := append(other[0:4:4], frags[1], frags[3], frags[4])
+	good, err := ConsistentWith(set, md)
+	if err != nil {
+		t.Fatalf("ConsistentWith: %v", err)
+	}
+	if len(good) != 3 {
+		t.Errorf("ConsistentWith: want 3 fragments got %d", len(good))
+	}
+	if zot, err := Reconstruct(good); err != nil || !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: want %q got %q, %v", data, zot, err)
+	}
+	if _, err := ConsistentWith(other, md); err != ErrTooFewFragments {
+		t.Errorf("ConsistentWith of other set: want %v got %v", ErrTooFewFragments, err)
+	}
+	big := frags[2].Clone()
+	big.Index = 6
+	if md.Match(big) {
+		t.Errorf("Match: Index %d beyond N %d", big.Index, md.N)
+	}
+}
@@ -0,0 +1,84 @@
+package ida
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// EncodeParallel is like [Encode] but computes the fragments in parallel,
+// using GOMAXPROCS goroutines.
+func EncodeParallel(data []byte, m, n int) ([]*Frag, error) {
+	return NewEncoder(m, nil).EncodeParallel(data, n)
+}
+
+// EncodeParallel is like [Encoder.Encode] but computes the fragments in parallel,
+// using GOMAXPROCS goroutines.
+// The encoding rows are drawn from e's source before any goroutine starts,
+// so the result is the same as Encode's for the same source.
+func (e *Encoder) EncodeParallel(data []byte, n int) ([]*Frag, error) {
+	if err := e.check(n); err != nil {
+		return nil, err
+	}
+	rows, id := e.rows(n), e.setID()
+	dg := digest(data)
+	frags := make([]*Frag, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := min(runtime.GOMAXPROCS(0), n); w > 0; w-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				frags[i] = newFrag(int64(len(data)), rows[i], e.encode(data, rows[i]), slices.Clone(dg))
+				frags[i].member(i+1, id)
+			}
+		}()
+	}
+	for i := range frags {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return frags, nil
+}
+
+// ReconstructParallel is like [Reconstruct] but decodes the columns of the fragments' Enc values
+// in parallel, using the given number of goroutines, each writing a distinct part of the result.
+// If workers is not positive, it uses GOMAXPROCS of them.
+func ReconstructParallel(frags []*Frag, workers int) ([]byte, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	d, err := newDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	dlen, err := d.size()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, dlen)
+	workers = max(min(workers, d.fraglen), 1)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		k0 := i * d.fraglen / workers
+		k1 := (i + 1) * d.fraglen / workers
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.decodeColumns(out, k0, k1)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := d.check(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
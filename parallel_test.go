@@ -0,0 +1,75 @@
+package ida
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestReconstructParallel(t *testing.T) {
+	for _, size := range []int{0, 1, 13, 1000, 100 * 1024} {
+		data := make([]byte, size)
+		rand.Read(data)
+		frags, err := Encode(data, 7, 9)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		for _, workers := range []int{0, 1, 3, 64} {
+			zot, err := ReconstructParallel(frags[1:8], workers)
+			if err != nil {
+				t.Errorf("size %d workers %d: %v", size, workers, err)
+				continue
+			}
+			if !bytes.Equal(zot, data) {
+				t.Errorf("size %d workers %d: data differs", size, workers)
+			}
+		}
+	}
+}
+
+func TestEncodeParallel(t *testing.T) {
+	data := make([]byte, 100*1024+3)
+	rand.Read(data)
+	want, err := NewEncoder(5, rand.NewSource(7)).Encode(data, 12)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := NewEncoder(5, rand.NewSource(7)).EncodeParallel(data, 12)
+	if err != nil {
+		t.Fatalf("EncodeParallel: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("EncodeParallel differs from Encode")
+	}
+	zot, err := Reconstruct(got[7:])
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if !bytes.Equal(zot, data) {
+		t.Errorf("Reconstruct: data differs")
+	}
+	if _, err := EncodeParallel(data, 3, 2); err != ErrInvalidN {
+		t.Errorf("EncodeParallel(data, 3, 2): want %v got %v", ErrInvalidN, err)
+	}
+}
+
+func BenchmarkEncodeParallel(b *testing.B) {
+	data := make([]byte, 4<<20)
+	rand.Read(data)
+	const m, n = 16, 32
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Encode(data, m, n); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := EncodeParallel(data, m, n); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
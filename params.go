@@ -0,0 +1,76 @@
+package ida
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrBadProbability = errors.New("probability out of range")
+	ErrNoParams       = errors.New("no parameters meet the durability target")
+)
+
+// LossProbability returns the probability that data encoded as n fragments, any m of which reconstruct it,
+// is lost, when each fragment fails independently with probability p:
+// the upper tail of the binomial distribution, the probability that more than n-m fail.
+func LossProbability(p float64, m, n int) float64 {
+	if m < 1 || n < m || p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return 1
+	}
+	loss := 0.0
+	for j := n; j > n-m; j-- { // smallest terms first
+		loss += binom(n, j, p)
+	}
+	return min(loss, 1)
+}
+
+// binom returns the binomial probability of exactly j failures in n, each with probability p, 0 < p < 1,
+// computed with logarithms so that it does not overflow or underflow early.
+func binom(n, j int, p float64) float64 {
+	lc, _ := math.Lgamma(float64(n + 1))
+	lj, _ := math.Lgamma(float64(j + 1))
+	lk, _ := math.Lgamma(float64(n - j + 1))
+	return math.Exp(lc - lj - lk + float64(j)*math.Log(p) + float64(n-j)*math.Log1p(-p))
+}
+
+// SuggestParams returns the parameters m and n, with n at most maxN, of least redundancy n/m
+// such that data encoded with them survives with at least the given probability (durability),
+// for instance 0.99999999999 for eleven nines, when each fragment fails independently with probability p
+// over the same period, as computed by [LossProbability].
+// Of parameters with the same redundancy, it returns those with the smallest n.
+// Since larger sets can meet a target with less redundancy, the result is often near maxN;
+// a smaller maxN trades storage for fewer fragments.
+// It returns ErrBadProbability unless 0 ≤ p < 1 and 0 < durability < 1,
+// ErrInvalidN if maxN < 1, and ErrNoParams if no m and n up to maxN meet the target.
+func SuggestParams(p, durability float64, maxN int) (m, n int, err error) {
+	if !(p >= 0 && p < 1) || !(durability > 0 && durability < 1) {
+		return 0, 0, ErrBadProbability
+	}
+	if maxN < 1 {
+		return 0, 0, ErrInvalidN
+	}
+	eps := 1 - durability
+	for nn := 1; nn <= maxN; nn++ {
+		// the tail sum grows as m does, so find the largest m with the loss within eps
+		mm, loss := 0, 0.0
+		for j := nn; j >= 1; j-- {
+			if p > 0 {
+				loss += binom(nn, j, p)
+			}
+			if loss > eps {
+				break
+			}
+			mm = nn - j + 1 // losing j-1 or fewer is survivable with m = n-j+1
+		}
+		if mm > 0 && (m == 0 || mm*n > m*nn) {
+			m, n = mm, nn
+		}
+	}
+	if m == 0 {
+		return 0, 0, ErrNoParams
+	}
+	return m, n, nil
+}
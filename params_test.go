@@ -0,0 +1,78 @@
+package ida
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLossProbability(t *testing.T) {
+	for _, c := range []struct {
+		p    float64
+		m, n int
+		want float64
+	}{
+		{0.1, 1, 1, 0.1},
+		{0.1, 1, 2, 0.01},
+		{0.1, 1, 3, 0.001},
+		{0.1, 2, 2, 0.19},             // 1 - 0.9²
+		{0.1, 3, 5, 0.00856},          // 10·0.1³·0.9² + 5·0.1⁴·0.9 + 0.1⁵
+		{0.5, 5, 10, 0.376953125},     // 386/1024
+		{0.01, 10, 14, 1.8568943e-07}, // dominated by 2002·0.01⁵·0.99⁹
+		{0, 3, 5, 0},
+		{1, 3, 5, 1},
+	} {
+		got := LossProbability(c.p, c.m, c.n)
+		if math.Abs(got-c.want) > 1e-6*c.want {
+			t.Errorf("LossProbability(%g, %d, %d): want %g got %g", c.p, c.m, c.n, c.want, got)
+		}
+	}
+	// against enumeration of every pattern of failures
+	for n := 1; n <= 10; n++ {
+		for m := 1; m <= n; m++ {
+			p := 0.07
+			want := 0.0
+			for s := 0; s < 1<<n; s++ {
+				failed := 0
+				for b := s; b != 0; b &= b - 1 {
+					failed++
+				}
+				if n-failed < m {
+					want += math.Pow(p, float64(failed)) * math.Pow(1-p, float64(n-failed))
+				}
+			}
+			if got := LossProbability(p, m, n); math.Abs(got-want) > 1e-12 {
+				t.Errorf("LossProbability(%g, %d, %d): want %g got %g", p, m, n, want, got)
+			}
+		}
+	}
+}
+
+func TestSuggestParams(t *testing.T) {
+	for _, c := range []struct {
+		p, durability float64
+		maxN          int
+		m, n          int
+	}{
+		{0, 0.999, 10, 1, 1},
+		{0.1, 0.99, 2, 1, 2},             // 0.1² = 0.01
+		{0.1, 0.99, 5, 3, 5},             // 3/5 loses 0.00856
+		{0.01, 0.99999999999, 16, 9, 16}, // 12870·0.01⁸ < 10⁻¹¹ < 11440·0.01⁷
+	} {
+		m, n, err := SuggestParams(c.p, c.durability, c.maxN)
+		if err != nil || m != c.m || n != c.n {
+			t.Errorf("SuggestParams(%g, %g, %d): want %d, %d got %d, %d, %v", c.p, c.durability, c.maxN, c.m, c.n, m, n, err)
+			continue
+		}
+		if loss := LossProbability(c.p, m, n); loss > 1-c.durability {
+			t.Errorf("SuggestParams(%g, %g, %d): %d of %d loses %g", c.p, c.durability, c.maxN, m, n, loss)
+		}
+	}
+	if _, _, err := SuggestParams(0.5, 0.99999999999, 4); err != ErrNoParams {
+		t.Errorf("impossible target: want %v got %v", ErrNoParams, err)
+	}
+	for _, bad := range [][2]float64{{-0.1, 0.9}, {1, 0.9}, {0.1, 1}, {0.1, 0}, {math.NaN(), 0.9}} {
+		if _, _, err := SuggestParams(bad[0], bad[1], 10); err != ErrBadProbability {
+			t.Errorf("SuggestParams(%g, %g): want %v got %v", bad[0], bad[1], ErrBadProbability, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ida
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+)
+
+var ErrInvalidRange = errors.New("byte range outside the data")
+
+// ReconstructRange returns bytes [off, off+length) of the data encoded by the given consistent set of fragments,
+// decoding only the Enc columns that cover them (each column holds 2m consecutive bytes of the data).
+// The offset is 64 bits, as Frag.Len is, so that any part of a large object can be read.
+// It returns ErrInvalidRange if the range is not within the data.
+// Since the data as a whole is not decoded, it cannot be checked against the fragments' Digest.
+func ReconstructRange(frags []*Frag, off int64, length int) ([]byte, error) {
+	d, err := newDecoder(frags)
+	if err != nil {
+		return nil, err
+	}
+	dlen := d.frags[0].Len
+	if off < 0 || length < 0 || off > dlen || int64(length) > dlen-off {
+		return nil, ErrInvalidRange
+	}
+	end := off + int64(length)
+	col := int64(2 * d.m)
+	out := make([]byte, 0, length)
+	w := make([]Field, d.m)
+	cb := make([]byte, col)
+	for k := off / col; k*col < end; k++ {
+		if err := d.column(int(k), w); err != nil {
+			return nil, err
+		}
+		unpack(cb, w)
+		base := k * col
+		out = append(out, cb[max(off-base, 0):min(end-base, col)]...)
+	}
+	return out, nil
+}
+
+// ReconstructPrefix is a best-effort form of [Reconstruct] for fragments some of which have been truncated,
+// losing the end of their Enc values, as by a storage node that failed part way through a write.
+// Consistent and Reconstruct would reject them; ReconstructPrefix instead chooses m fragments
+// with independent rows that have the longest Enc values, and decodes the columns they all have,
+// giving the longest prefix of the data that it can, the whole of it if m fragments are intact.
+// The parameters M, Len, Digest and SetID are decided by majority vote, as by Reconstruct,
+// but the CRC of a truncated fragment cannot be checked, nor can a prefix be checked against the Digest.
+// It returns the prefix, its length being the number of bytes recovered, and whether that is all the data.
+func ReconstructPrefix(frags []*Frag) (data []byte, complete bool, err error) {
+	ds := []val[int64]{}
+	ms := []val[int]{}
+	dgs := []val[string]{}
+	ids := []val[uint64]{}
+	for _, f := range frags {
+		if f != nil {
+			ok := f.Verify()
+			ds = addval(ds, f.Len, ok)
+			ms = addval(ms, f.M, ok)
+			dgs = addval(dgs, string(f.Digest), ok)
+			if f.SetID != 0 {
+				ids = addval(ids, f.SetID, ok)
+			}
+		}
+	}
+	dlen, ok1 := mostly(ds)
+	m, ok2 := mostly(ms)
+	dg, ok3 := mostly(dgs)
+	id, ok4 := setvote(ids)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, false, ErrUnstableParameters
+	}
+	if m < 1 || dlen < 0 {
+		return nil, false, ErrTooFewFragments
+	}
+	need := int(enclen(dlen, m))
+	var cand []*Frag
+	for _, f := range frags {
+		if f == nil || f.M != m || len(f.A) != m || f.Len != dlen || string(f.Digest) != dg || f.SetID != 0 && f.SetID != id {
+			continue
+		}
+		if badrow(f.A) || slices.ContainsFunc(f.Enc, func(v int) bool { return v < 0 || v >= Prime }) {
+			continue
+		}
+		if len(f.Enc) >= need && badcrc(f) {
+			continue // intact, but corrupt
+		}
+		cand = append(cand, f)
+	}
+	slices.SortStableFunc(cand, func(f, g *Frag) int { return cmp.Compare(len(g.Enc), len(f.Enc)) })
+	var b basis
+	sel := make([]*Frag, 0, m)
+	for _, f := range cand {
+		if len(sel) < m && b.add(f.A) {
+			sel = append(sel, f)
+		}
+	}
+	if len(sel) < m {
+		return nil, false, ErrTooFewFragments
+	}
+	fraglen := min(len(sel[m-1].Enc), need)
+	complete = fraglen == need
+	plen := dlen
+	if !complete {
+		plen = int64(2 * m * fraglen)
+	}
+	views := make([]*Frag, m)
+	for i, f := range sel {
+		views[i] = &Frag{Len: plen, M: m, A: f.A, Enc: f.Enc[0:fraglen], Index: f.Index, SetID: f.SetID}
+		if complete {
+			views[i].Digest = f.Digest
+		}
+	}
+	data, err = Reconstruct(views)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, complete, nil
+}
@@ -0,0 +1,78 @@
+package ida
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestReconstructRange(t *testing.T) {
+	data := make([]byte, 1001)
+	rand.Read(data)
+	frags, err := Encode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, r := range [][2]int{{0, 0}, {0, 1001}, {0, 6}, {5, 1}, {6, 6}, {7, 100}, {994, 7}, {1000, 1}, {1001, 0}} {
+		off, n := r[0], r[1]
+		got, err := ReconstructRange(frags[1:4], int64(off), n)
+		if err != nil {
+			t.Errorf("[%d, %d): %v", off, off+n, err)
+			continue
+		}
+		if !bytes.Equal(got, data[off:off+n]) {
+			t.Errorf("[%d, %d): data differs", off, off+n)
+		}
+	}
+	for _, r := range [][2]int{{-1, 1}, {0, -1}, {0, 1002}, {1000, 2}, {1002, 0}} {
+		if _, err := ReconstructRange(frags, int64(r[0]), r[1]); err != ErrInvalidRange {
+			t.Errorf("[%d, %d): want %v got %v", r[0], r[0]+r[1], ErrInvalidRange, err)
+		}
+	}
+}
+
+func TestReconstructPrefix(t *testing.T) {
+	data := make([]byte, 101)
+	for i := range data {
+		data[i] = byte(i*13 + 1)
+	}
+	frags, err := Encode(data, 3, 5) // 17 columns
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	truncate := func(f *Frag, n int) *Frag {
+		g := f.Clone()
+		g.Enc = g.Enc[0:n]
+		return g
+	}
+
+	// three intact fragments give all the data
+	set := []*Frag{truncate(frags[0], 5), frags[1], frags[2], truncate(frags[3], 7), frags[4]}
+	zot, complete, err := ReconstructPrefix(set)
+	if err != nil || !complete || !bytes.Equal(zot, data) {
+		t.Errorf("ReconstructPrefix with 3 intact: want %q, true got %q, %v, %v", data, zot, complete, err)
+	}
+
+	// with two, the prefix covered by the longer of the truncated ones
+	set = []*Frag{truncate(frags[0], 5), frags[1], nil, truncate(frags[3], 7), frags[4]}
+	if _, err := Reconstruct(set); err == nil {
+		t.Errorf("Reconstruct of truncated fragments: want error")
+	}
+	zot, complete, err = ReconstructPrefix(set)
+	if err != nil || complete || !bytes.Equal(zot, data[0:2*3*7]) {
+		t.Errorf("ReconstructPrefix with 2 intact: want %q, false got %q, %v, %v", data[0:42], zot, complete, err)
+	}
+
+	// a corrupt intact fragment is not used
+	bad := frags[1].Clone()
+	bad.Enc[16] ^= 1
+	set = []*Frag{truncate(frags[0], 5), bad, truncate(frags[2], 3), truncate(frags[3], 7), frags[4]}
+	zot, complete, err = ReconstructPrefix(set)
+	if err != nil || complete || !bytes.Equal(zot, data[0:2*3*5]) {
+		t.Errorf("ReconstructPrefix with a corrupt fragment: want %q, false got %q, %v, %v", data[0:30], zot, complete, err)
+	}
+
+	if _, _, err := ReconstructPrefix(set[0:2]); err != ErrTooFewFragments {
+		t.Errorf("ReconstructPrefix of 2: want %v got %v", ErrTooFewFragments, err)
+	}
+}
@@ -0,0 +1,217 @@
+package ida
+
+import (
+	"errors"
+	"io"
+)
+
+// IDA is an adapter giving a systematic dispersal the Split, Encode, Reconstruct, Verify and Join
+// methods of the erasure coders in the style of github.com/klauspost/reedsolomon,
+// so that either can be used behind the same interface.
+//
+// The arithmetic is in [GF65536], so that every encoded word fits in two bytes,
+// and all shards are the same size: the Enc values of a fragment, as big-endian 16-bit words.
+// The first m shards are the data shards: shard i holds words i, i+m, i+2m, ... of the data,
+// packed as by [PackWords], and padded with zeros to a whole number of columns of m words,
+// so that shard i is the Enc of the systematic fragment with row i of the identity matrix.
+// Note that this interleaving differs from reedsolomon's Split, which gives each shard a contiguous
+// piece of the data; shards are interchangeable between the two only through Split and Join.
+// The remaining n-m parity shards have rows of a Cauchy matrix over GF65536,
+// row j being 1/(x⊕y) for x = m+j and y = 0 to m-1, so any m shards are enough to recover the others.
+type IDA struct {
+	m, n int
+	rows [][]Field // the encoding rows of all n shards
+}
+
+var (
+	ErrShortData  = errors.New("not enough data to fill the shards")
+	ErrShardCount = errors.New("wrong number of shards")
+	ErrShardSize  = errors.New("shards of differing or odd sizes")
+)
+
+// NewIDA returns an IDA with the given numbers of data and parity shards.
+// It returns an error if there are no data shards, parity shards is negative, or the total exceeds the size of GF65536.
+func NewIDA(dataShards, parityShards int) (*IDA, error) {
+	m, n := dataShards, dataShards+parityShards
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	if parityShards < 0 || n > 1<<16 {
+		return nil, ErrInvalidN
+	}
+	var gf GF65536
+	rows := make([][]Field, n)
+	for i := range rows {
+		a := make([]Field, m)
+		if i < m {
+			a[i] = 1
+		} else {
+			for j := range a {
+				a[j] = gf.Div(1, gf.Add(Field(i), Field(j)))
+			}
+		}
+		rows[i] = a
+	}
+	return &IDA{m: m, n: n, rows: rows}, nil
+}
+
+// Split returns the n shards of data, the data shards filled as described for [IDA],
+// and the parity shards allocated but zero, to be computed by Encode.
+// It returns ErrShortData if data is empty.
+func (r *IDA) Split(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrShortData
+	}
+	size := 2 * enclen(int64(len(data)), r.m)
+	words := PackWords(data)
+	shards := make([][]byte, r.n)
+	for i := range shards {
+		shards[i] = make([]byte, size)
+	}
+	for w, b := range words {
+		s := shards[w%r.m][2*(w/r.m):]
+		s[0], s[1] = byte(b>>8), byte(b)
+	}
+	return shards, nil
+}
+
+// Encode computes the parity shards from the data shards, which must all be present,
+// allocating any parity shards that are nil or empty.
+func (r *IDA) Encode(shards [][]byte) error {
+	size, err := r.check(shards, true)
+	if err != nil {
+		return err
+	}
+	for i := r.m; i < r.n; i++ {
+		if len(shards[i]) == 0 {
+			shards[i] = make([]byte, size)
+		}
+		r.parity(shards, i, shards[i])
+	}
+	return nil
+}
+
+// Verify returns true if the parity shards agree with the data shards. All shards must be present.
+func (r *IDA) Verify(shards [][]byte) (bool, error) {
+	size, err := r.check(shards, true)
+	if err != nil {
+		return false, err
+	}
+	p := make([]byte, size)
+	for i := r.m; i < r.n; i++ {
+		if len(shards[i]) == 0 {
+			return false, ErrTooFewFragments
+		}
+		r.parity(shards, i, p)
+		if string(p) != string(shards[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Reconstruct recreates the missing shards, those that are nil or empty, from any m of the others,
+// using [ReconstructIn] on fragments made from them.
+// It returns ErrTooFewFragments if fewer than m shards are present.
+func (r *IDA) Reconstruct(shards [][]byte) error {
+	size, err := r.check(shards, false)
+	if err != nil {
+		return err
+	}
+	frags := make([]*Frag, 0, r.m)
+	missing := false
+	for i, s := range shards {
+		if len(s) == 0 {
+			missing = true
+			continue
+		}
+		if len(frags) < r.m {
+			enc := make([]int, size/2)
+			for k := range enc {
+				enc[k] = int(s[2*k])<<8 | int(s[2*k+1])
+			}
+			frags = append(frags, &Frag{Len: int64(size * r.m), M: r.m, A: r.rows[i], Enc: enc})
+		}
+	}
+	if !missing {
+		return nil
+	}
+	if len(frags) < r.m {
+		return ErrTooFewFragments
+	}
+	data, err := ReconstructIn(GF65536{}, frags)
+	if err != nil {
+		return err
+	}
+	for i, s := range shards {
+		if len(s) != 0 {
+			continue
+		}
+		s = make([]byte, size)
+		for k, v := range gfEncode(data, r.rows[i]) {
+			s[2*k], s[2*k+1] = byte(v>>8), byte(v)
+		}
+		shards[i] = s
+	}
+	return nil
+}
+
+// Join writes the first outSize bytes of the data held by the data shards to dst.
+func (r *IDA) Join(dst io.Writer, shards [][]byte, outSize int) error {
+	size, err := r.check(shards, true)
+	if err != nil {
+		return err
+	}
+	if outSize < 0 || outSize > size*r.m {
+		return ErrShortData
+	}
+	out := make([]byte, 0, outSize)
+	for k := 0; k < size && len(out) < outSize; k += 2 {
+		for i := 0; i < r.m; i++ {
+			out = append(out, shards[i][k], shards[i][k+1])
+		}
+	}
+	_, err = dst.Write(out[0:outSize])
+	return err
+}
+
+// check returns the size of the shards, after checking there are n of them,
+// the data shards are all present if needdata, and the shards present are all the same, even, size.
+func (r *IDA) check(shards [][]byte, needdata bool) (int, error) {
+	if len(shards) != r.n {
+		return 0, ErrShardCount
+	}
+	size := 0
+	for i, s := range shards {
+		switch {
+		case len(s) == 0:
+			if needdata && i < r.m {
+				return 0, ErrTooFewFragments
+			}
+		case size == 0:
+			size = len(s)
+		case len(s) != size:
+			return 0, ErrShardSize
+		}
+	}
+	if size == 0 {
+		return 0, ErrTooFewFragments
+	}
+	if size%2 != 0 {
+		return 0, ErrShardSize
+	}
+	return size, nil
+}
+
+// parity sets p to parity shard i computed from the data shards.
+func (r *IDA) parity(shards [][]byte, i int, p []byte) {
+	var gf GF65536
+	a := r.rows[i]
+	for k := 0; k < len(p); k += 2 {
+		c := Field(0)
+		for j, s := range shards[0:r.m] {
+			c ^= gf.Mul(a[j], Field(s[k])<<8|Field(s[k+1]))
+		}
+		p[k], p[k+1] = byte(c>>8), byte(c)
+	}
+}
@@ -0,0 +1,84 @@
+package ida
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestIDAAdapter(t *testing.T) {
+	r, err := NewIDA(4, 3)
+	if err != nil {
+		t.Fatalf("NewIDA: %v", err)
+	}
+	for _, size := range []int{1, 7, 8, 9, 1000, 4097} {
+		data := make([]byte, size)
+		rand.Read(data)
+		shards, err := r.Split(data)
+		if err != nil {
+			t.Fatalf("Split: %v", err)
+		}
+		if len(shards) != 7 || len(shards[0]) != 2*int(enclen(int64(size), 4)) {
+			t.Fatalf("size %d: Split: %d shards of %d bytes", size, len(shards), len(shards[0]))
+		}
+		if err := r.Encode(shards); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if ok, err := r.Verify(shards); !ok || err != nil {
+			t.Errorf("size %d: Verify: %v %v", size, ok, err)
+		}
+
+		// the shards are the Enc values of GF65536 fragments, which ReconstructIn accepts
+		frags := make([]*Frag, 4)
+		for i, j := range []int{6, 1, 4, 3} {
+			enc := make([]int, len(shards[j])/2)
+			for k := range enc {
+				enc[k] = int(shards[j][2*k])<<8 | int(shards[j][2*k+1])
+			}
+			frags[i] = &Frag{Len: int64(size), M: 4, A: r.rows[j], Enc: enc}
+		}
+		if out, err := ReconstructIn(GF65536{}, frags); err != nil || !bytes.Equal(out, data) {
+			t.Errorf("size %d: ReconstructIn of shards: data mismatch (%v)", size, err)
+		}
+
+		for _, lost := range [][]int{{0}, {0, 1, 2}, {4, 5, 6}, {1, 3, 5}} {
+			damaged := make([][]byte, len(shards))
+			copy(damaged, shards)
+			for _, i := range lost {
+				damaged[i] = nil
+			}
+			if err := r.Reconstruct(damaged); err != nil {
+				t.Errorf("size %d lost %v: Reconstruct: %v", size, lost, err)
+				continue
+			}
+			for i := range shards {
+				if !bytes.Equal(damaged[i], shards[i]) {
+					t.Errorf("size %d lost %v: shard %d differs", size, lost, i)
+				}
+			}
+			var buf bytes.Buffer
+			if err := r.Join(&buf, damaged, size); err != nil || !bytes.Equal(buf.Bytes(), data) {
+				t.Errorf("size %d lost %v: Join: data mismatch (%v)", size, lost, err)
+			}
+		}
+		shards[5][0] ^= 1
+		if ok, _ := r.Verify(shards); ok {
+			t.Errorf("size %d: Verify of damaged parity: ok", size)
+		}
+		for _, i := range []int{0, 1, 2, 3} {
+			shards[i] = nil
+		}
+		if err := r.Reconstruct(shards); err != ErrTooFewFragments {
+			t.Errorf("size %d: too few shards: want %v got %v", size, ErrTooFewFragments, err)
+		}
+	}
+	if _, err := r.Split(nil); err != ErrShortData {
+		t.Errorf("Split(nil): want %v got %v", ErrShortData, err)
+	}
+	if err := r.Encode(make([][]byte, 3)); err != ErrShardCount {
+		t.Errorf("Encode: want %v got %v", ErrShardCount, err)
+	}
+	if _, err := NewIDA(0, 1); err != ErrInvalidM {
+		t.Errorf("NewIDA(0, 1): want %v got %v", ErrInvalidM, err)
+	}
+}
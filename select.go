@@ -0,0 +1,37 @@
+package ida
+
+// SelectIndependent returns m of the given fragments whose encoding rows are linearly independent,
+// which Reconstruct is therefore guaranteed to accept, where m is that of the first fragment.
+// The rows are chosen greedily in order, by Gaussian elimination, skipping any that depend
+// on those already chosen (including duplicates), and fragments with the wrong number of elements in A.
+// It returns ErrInconsistentMatrix if no such set can be found.
+func SelectIndependent(frags []*Frag) ([]*Frag, error) {
+	used, err := independent(frags)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Frag, len(used))
+	for i, j := range used {
+		out[i] = frags[j]
+	}
+	return out, nil
+}
+
+// independent returns the indices in frags of the fragments chosen by SelectIndependent.
+func independent(frags []*Frag) ([]int, error) {
+	if len(frags) == 0 || frags[0] == nil {
+		return nil, ErrTooFewFragments
+	}
+	m := frags[0].M
+	var b basis
+	var used []int
+	for j, f := range frags {
+		if f != nil && len(f.A) == m && b.add(f.A) {
+			used = append(used, j)
+			if len(used) == m {
+				return used, nil
+			}
+		}
+	}
+	return nil, ErrInconsistentMatrix
+}
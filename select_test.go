@@ -0,0 +1,39 @@
+package ida
+
+import (
+	"testing"
+)
+
+func TestSelectIndependent(t *testing.T) {
+	data := []byte("choose wisely")
+	a := []Field{1, 2, 3}
+	b := []Field{2, 4, 6} // 2a
+	c := []Field{5, 1, 7}
+	d := []Field{7, 5, 13} // 2a+c
+	e := []Field{1, 1, 1}
+	var frags []*Frag
+	for _, r := range [][]Field{a, b, a, c, d, e} {
+		f, err := FragmentWith(data, r)
+		if err != nil {
+			t.Fatalf("FragmentWith: %v", err)
+		}
+		frags = append(frags, f)
+	}
+	sel, err := SelectIndependent(frags)
+	if err != nil {
+		t.Fatalf("SelectIndependent: %v", err)
+	}
+	if len(sel) != 3 || sel[0] != frags[0] || sel[1] != frags[3] || sel[2] != frags[5] {
+		t.Errorf("SelectIndependent: chose the wrong fragments")
+	}
+	zot, err := Reconstruct(sel)
+	if err != nil {
+		t.Fatalf("Reconstruct: %v", err)
+	}
+	if string(zot) != string(data) {
+		t.Errorf("Reconstruct: want %q got %q", data, zot)
+	}
+	if _, err := SelectIndependent(frags[0:5]); err != ErrInconsistentMatrix {
+		t.Errorf("SelectIndependent of rank 2: want %v got %v", ErrInconsistentMatrix, err)
+	}
+}
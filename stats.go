@@ -0,0 +1,18 @@
+package ida
+
+// Stats counts the operations done in Z(Prime) by the package, including those of Invert, Reconstruct and Fragment,
+// when it is built with the idastats tag; otherwise the counting code is not compiled, and the counts stay zero.
+// Subtraction counts as addition. A division counts as one Div and the Mul by the inverse it does;
+// built with the idanotab or crypto tags, the inverse a^(Prime-2) = a^65535 takes a further 32:
+// 16 squarings and 16 products, since the exponent is sixteen 1 bits.
+// The counts are kept with atomic operations, so they include those of all goroutines.
+type Stats struct {
+	Add uint64
+	Mul uint64
+	Div uint64
+}
+
+// Sub returns the counts in s less those in t, typically an earlier reading.
+func (s Stats) Sub(t Stats) Stats {
+	return Stats{Add: s.Add - t.Add, Mul: s.Mul - t.Mul, Div: s.Div - t.Div}
+}
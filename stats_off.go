@@ -0,0 +1,20 @@
+//go:build !idastats
+
+package ida
+
+// StatsEnabled is true if the package counts field operations (see [Stats]).
+const StatsEnabled = false
+
+// The counting functions do nothing, and calls to them compile to nothing.
+
+func countAdd() {}
+func countMul() {}
+func countDiv() {}
+
+// ReadStats returns the counts of field operations done so far, which are zero without the idastats tag.
+func ReadStats() Stats {
+	return Stats{}
+}
+
+// ResetStats sets the counts of field operations to zero.
+func ResetStats() {}
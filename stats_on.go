@@ -0,0 +1,28 @@
+//go:build idastats
+
+package ida
+
+import "sync/atomic"
+
+// StatsEnabled is true if the package counts field operations (see [Stats]).
+const StatsEnabled = true
+
+var stats struct {
+	add, mul, div atomic.Uint64
+}
+
+func countAdd() { stats.add.Add(1) }
+func countMul() { stats.mul.Add(1) }
+func countDiv() { stats.div.Add(1) }
+
+// ReadStats returns the counts of field operations done so far.
+func ReadStats() Stats {
+	return Stats{Add: stats.add.Load(), Mul: stats.mul.Load(), Div: stats.div.Load()}
+}
+
+// ResetStats sets the counts of field operations to zero.
+func ResetStats() {
+	stats.add.Store(0)
+	stats.mul.Store(0)
+	stats.div.Store(0)
+}
@@ -0,0 +1,57 @@
+package ida
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	if !StatsEnabled {
+		if s := ReadStats(); s != (Stats{}) {
+			t.Errorf("counts without idastats: %+v", s)
+		}
+		t.Skip("built without idastats")
+	}
+	rnd := rand.New(rand.NewSource(72))
+	inversion := func(m int) Stats {
+		a := NewMatrix(m)
+		for i := range a {
+			a[i] = randomVec(rnd, m)
+		}
+		s0 := ReadStats()
+		if _, err := a.Invert(); err != nil {
+			t.Fatalf("Invert: %v", err)
+		}
+		return ReadStats().Sub(s0)
+	}
+	// Gauss-Jordan elimination of m×2m does about m³ multiply-adds, and O(m²) divisions;
+	// count the additions, since without the inverse table, divisions add multiplications
+	s8, s16, s32 := inversion(8), inversion(16), inversion(32)
+	for _, c := range []struct{ a, b Stats }{{s8, s16}, {s16, s32}} {
+		if r := float64(c.b.Add) / float64(c.a.Add); r < 7 || r > 9 {
+			t.Errorf("doubling m multiplied Add count by %.2f, not about 8: %+v %+v", r, c.a, c.b)
+		}
+	}
+	if s16.Div < 16 || s16.Div > 4*16*16 {
+		t.Errorf("m=16: %d divisions", s16.Div)
+	}
+
+	// Fragment does m multiplications and additions per Enc value
+	data := make([]byte, 1000)
+	s0 := ReadStats()
+	f := NewEncoder(5, rnd).Fragment(data)
+	s := ReadStats().Sub(s0)
+	if want := uint64(5 * len(f.Enc)); s.Mul < want {
+		t.Errorf("Fragment: %d multiplications, want at least %d", s.Mul, want)
+	}
+	// a division is one Mul with the inverse table, and 32 more without it
+	s0 = ReadStats()
+	Field(7).div(12345)
+	if s := ReadStats().Sub(s0); s.Div != 1 || s.Mul != 1 && s.Mul != 1+32 {
+		t.Errorf("one division: %+v", s)
+	}
+	ResetStats()
+	if s := ReadStats(); s != (Stats{}) {
+		t.Errorf("after ResetStats: %+v", s)
+	}
+}
@@ -0,0 +1,148 @@
+package ida
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"slices"
+)
+
+// streamBlock is the approximate size of the blocks read by FragmentStream.
+const streamBlock = 64 * 1024
+
+// FragmentStream returns n fragments of the data read from r until EOF,
+// any m of which are normally enough to reconstruct it,
+// as [Encode] would for the same data held in memory.
+// The data is read and encoded in blocks, so only the fragments need be held in memory.
+func FragmentStream(r io.Reader, m, n int) ([]*Frag, error) {
+	return NewEncoder(m, nil).FragmentStream(r, n)
+}
+
+// FragmentStreamCtx is like [FragmentStream] but stops, returning ctx.Err(), if ctx is done
+// before the data has all been read and encoded. It checks ctx before reading each block.
+func FragmentStreamCtx(ctx context.Context, r io.Reader, m, n int) ([]*Frag, error) {
+	return NewEncoder(m, nil).FragmentStreamCtx(ctx, r, n)
+}
+
+// FragmentStream returns n fragments of the data read from r until EOF,
+// as for the package function [FragmentStream].
+func (e *Encoder) FragmentStream(r io.Reader, n int) ([]*Frag, error) {
+	return e.FragmentStreamCtx(context.Background(), r, n)
+}
+
+// FragmentStreamCtx is like [Encoder.FragmentStream] but with a context, as for the package function [FragmentStreamCtx].
+func (e *Encoder) FragmentStreamCtx(ctx context.Context, r io.Reader, n int) ([]*Frag, error) {
+	if err := e.check(n); err != nil {
+		return nil, err
+	}
+	frags := make([]*Frag, n)
+	rows, id := e.rows(n), e.setID()
+	for i, a := range rows {
+		frags[i] = &Frag{M: e.M, A: a, Enc: []int{}, Index: i + 1, SetID: id}
+	}
+	// each block but the last fills a whole number of columns, so the blocks' encodings concatenate
+	col := wordBytes(e.order()) * e.M
+	buf := make([]byte, max(streamBlock/col, 1)*col)
+	h := sha256.New()
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		nr, err := io.ReadFull(r, buf)
+		if nr > 0 {
+			h.Write(buf[0:nr])
+			for _, f := range frags {
+				f.Len += int64(nr)
+				f.Enc = append(f.Enc, e.encode(buf[0:nr], f.A)...)
+			}
+			if e.Progress != nil {
+				e.Progress(frags[0].Len, -1)
+			}
+		}
+		switch err {
+		case nil:
+			continue
+		case io.EOF, io.ErrUnexpectedEOF:
+			dg := h.Sum(nil)
+			for _, f := range frags {
+				f.Digest = slices.Clone(dg)
+				f.CRC = f.checksum()
+			}
+			return frags, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// ReconstructStream writes the data encoded by the given consistent set of fragments to w,
+// as [Reconstruct] would return it, but decoding and writing a block at a time,
+// so that memory use does not depend on the size of the data.
+// It returns the number of bytes written, which like Frag.Len is 64 bits so that the data can exceed
+// the largest slice, and any error, either from decoding or writing.
+// If the fragments turn out to be corrupt, some data might already have been written.
+// In particular, the data can only be checked against the fragments' Digest once it has all been written.
+func ReconstructStream(frags []*Frag, w io.Writer) (int64, error) {
+	return ReconstructStreamCtx(context.Background(), frags, w)
+}
+
+// ReconstructStreamCtx is like [ReconstructStream] but stops, returning ctx.Err()
+// with the number of bytes written so far, if ctx is done before the data has all been written.
+// It checks ctx before writing each block.
+func ReconstructStreamCtx(ctx context.Context, frags []*Frag, w io.Writer) (int64, error) {
+	return ReconstructStreamProgress(ctx, frags, w, nil)
+}
+
+// ReconstructStreamProgress is like [ReconstructStreamCtx] but if progress is not nil,
+// calls it after each block is written, with the number of bytes written so far, and the total.
+// The calls are made in turn by the calling goroutine.
+func ReconstructStreamProgress(ctx context.Context, frags []*Frag, w io.Writer, progress func(done, total int64)) (int64, error) {
+	d, err := newDecoder(frags)
+	if err != nil {
+		return 0, err
+	}
+	return d.stream(ctx, w, progress)
+}
+
+// stream writes the data to w a block at a time, checking it against the Digest at the end,
+// as described for ReconstructStreamProgress.
+func (d *decoder) stream(ctx context.Context, w io.Writer, progress func(done, total int64)) (int64, error) {
+	h := sha256.New()
+	col := 2 * d.m
+	buf := make([]byte, 0, max(streamBlock/col, 1)*col)
+	words := make([]Field, d.m)
+	left := d.frags[0].Len
+	nw := int64(0)
+	for k := 0; k < d.fraglen && left > 0; k++ {
+		if err := d.column(k, words); err != nil {
+			return nw, err
+		}
+		n := len(buf)
+		buf = buf[0 : n+col]
+		unpack(buf[n:], words)
+		if int64(len(buf)) > left {
+			buf = buf[0:left]
+		}
+		if len(buf) == cap(buf) || int64(len(buf)) == left {
+			if err := ctx.Err(); err != nil {
+				return nw, err
+			}
+			h.Write(buf)
+			n, err := w.Write(buf)
+			nw += int64(n)
+			left -= int64(n)
+			if err != nil {
+				return nw, err
+			}
+			if progress != nil {
+				progress(nw, d.frags[0].Len)
+			}
+			buf = buf[0:0]
+		}
+	}
+	if d.digest != nil && !bytes.Equal(h.Sum(nil), d.digest) {
+		return nw, ErrDigestMismatch
+	}
+	return nw, nil
+}
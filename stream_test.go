@@ -0,0 +1,162 @@
+package ida
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/iotest"
+)
+
+func TestFragmentStream(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 7, streamBlock - 1, streamBlock, 3*streamBlock + 5} {
+		data := make([]byte, size)
+		rand.Read(data)
+		frags, err := NewEncoder(5, rand.NewSource(1)).FragmentStream(iotest.HalfReader(bytes.NewReader(data)), 8)
+		if err != nil {
+			t.Errorf("size %d: FragmentStream: %v", size, err)
+			continue
+		}
+		for i, f := range frags {
+			g := fragment(data, f.A)
+			if f.Len != g.Len || !slices.Equal(f.Enc, g.Enc) {
+				t.Errorf("size %d: fragment %d differs from in-memory encoding", size, i)
+			}
+		}
+		zot, err := Reconstruct(frags[3:])
+		if err != nil {
+			t.Errorf("size %d: Reconstruct: %v", size, err)
+			continue
+		}
+		if !bytes.Equal(zot, data) {
+			t.Errorf("size %d: reconstructed data differs", size)
+		}
+	}
+}
+
+func TestReconstructStream(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 7, streamBlock - 1, streamBlock, 3*streamBlock + 5} {
+		data := make([]byte, size)
+		rand.Read(data)
+		frags, err := Encode(data, 6, 8)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		var out bytes.Buffer
+		n, err := ReconstructStream(frags[2:], &out)
+		if err != nil {
+			t.Errorf("size %d: ReconstructStream: %v", size, err)
+			continue
+		}
+		if n != int64(size) || !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("size %d: wrote %d bytes, data differs %v", size, n, !bytes.Equal(out.Bytes(), data))
+		}
+	}
+	data := make([]byte, 3*streamBlock)
+	f, err := FragmentWith(data, []Field{1})
+	if err != nil {
+		t.Fatalf("FragmentWith: %v", err)
+	}
+	f.Enc[len(f.Enc)-1] = int(MaxVal) // decodes to an impossible word
+	var out bytes.Buffer
+	n, err := ReconstructStream([]*Frag{f}, &out)
+	if !errors.Is(err, ErrCorruptOutput) {
+		t.Errorf("corrupt fragment: want %v got %v", ErrCorruptOutput, err)
+	}
+	if n != int64(out.Len()) || n >= int64(len(data)) {
+		t.Errorf("corrupt fragment: wrote %d bytes, reported %d", out.Len(), n)
+	}
+}
+
+// cancelReader cancels a context once it has been read beyond a given offset.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (cr *cancelReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if cr.n -= n; cr.n < 0 {
+		cr.cancel()
+	}
+	return n, err
+}
+
+// cancelWriter cancels a context once a given number of bytes have been written.
+type cancelWriter struct {
+	bytes.Buffer
+	n      int
+	cancel context.CancelFunc
+}
+
+func (cw *cancelWriter) Write(p []byte) (int, error) {
+	n, err := cw.Buffer.Write(p)
+	if cw.Len() >= cw.n {
+		cw.cancel()
+	}
+	return n, err
+}
+
+func TestStreamCtx(t *testing.T) {
+	data := make([]byte, 10*streamBlock)
+	rand.Read(data)
+	ctx, cancel := context.WithCancel(context.Background())
+	cr := &cancelReader{r: bytes.NewReader(data), n: 2 * streamBlock, cancel: cancel}
+	if _, err := FragmentStreamCtx(ctx, cr, 3, 5); err != context.Canceled {
+		t.Errorf("FragmentStreamCtx: want %v got %v", context.Canceled, err)
+	}
+	if cr.r.(*bytes.Reader).Len() == 0 {
+		t.Errorf("FragmentStreamCtx: read all the data despite cancellation")
+	}
+	frags, err := FragmentStreamCtx(context.Background(), bytes.NewReader(data), 3, 5)
+	if err != nil {
+		t.Fatalf("FragmentStreamCtx: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cw := &cancelWriter{n: streamBlock, cancel: cancel}
+	n, err := ReconstructStreamCtx(ctx, frags, cw)
+	if err != context.Canceled || n != int64(cw.Len()) || n >= int64(len(data)) {
+		t.Errorf("ReconstructStreamCtx: want %v got %v, wrote %d reported %d", context.Canceled, err, cw.Len(), n)
+	}
+	var out bytes.Buffer
+	if n, err := ReconstructStreamCtx(context.Background(), frags, &out); err != nil || n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("ReconstructStreamCtx: data differs, %v", err)
+	}
+}
+
+func TestStreamProgress(t *testing.T) {
+	data := make([]byte, 3*streamBlock+5)
+	rand.Read(data)
+	var done []int64
+	e := NewEncoder(2, nil)
+	e.Progress = func(n, total int64) {
+		if total != -1 {
+			t.Errorf("FragmentStream progress: want total -1 got %d", total)
+		}
+		done = append(done, n)
+	}
+	frags, err := e.FragmentStream(bytes.NewReader(data), 3)
+	if err != nil {
+		t.Fatalf("FragmentStream: %v", err)
+	}
+	if len(done) < 2 || done[len(done)-1] != int64(len(data)) || !slices.IsSorted(done) {
+		t.Errorf("FragmentStream progress: %v", done)
+	}
+	done = nil
+	_, err = ReconstructStreamProgress(context.Background(), frags, io.Discard, func(n, total int64) {
+		if total != int64(len(data)) {
+			t.Errorf("ReconstructStream progress: want total %d got %d", len(data), total)
+		}
+		done = append(done, n)
+	})
+	if err != nil {
+		t.Fatalf("ReconstructStreamProgress: %v", err)
+	}
+	if len(done) < 2 || done[len(done)-1] != int64(len(data)) || !slices.IsSorted(done) {
+		t.Errorf("ReconstructStream progress: %v", done)
+	}
+}
@@ -0,0 +1,92 @@
+package ida
+
+import (
+	"slices"
+)
+
+// In a systematic encoding, the first m of the n fragments have rows of the identity matrix,
+// so fragment i holds words i, i+m, i+2m, ... of the original data unchanged,
+// and the data can be recovered from those m without field arithmetic.
+// The remaining n-m parity fragments have rows of a Cauchy matrix,
+// every square submatrix of which is non-singular, so any m rows of the combined
+// encoding matrix are linearly independent.
+
+// SystematicEncode returns n fragments of data, the first m of which hold the data itself,
+// in m stripes, and the rest parity fragments, such that any m of the fragments are enough to reconstruct the data.
+// The fragments have Index values 1 to n.
+// It returns an error if m < 1 or n < m, or if n exceeds Prime.
+func SystematicEncode(data []byte, m, n int) ([]*Frag, error) {
+	if m < 1 {
+		return nil, ErrInvalidM
+	}
+	if n < m || n > Prime {
+		return nil, ErrInvalidN
+	}
+	dg := digest(data)
+	frags := make([]*Frag, n)
+	id := newSetID(nil)
+	for i := range frags {
+		a := make([]Field, m)
+		if i < m {
+			a[i] = 1
+		} else {
+			// a[j] = 1/(x-y[j]), where x = i and y[j] = j; x ≠ y[j] because i >= m
+			for j := range a {
+				a[j] = Field(1).div(Field(i).sub(Field(j)))
+			}
+		}
+		frags[i] = newFrag(int64(len(data)), a, encode(data, a), slices.Clone(dg))
+		frags[i].member(i+1, id)
+	}
+	return frags, nil
+}
+
+// Systematic returns (i, true) if f is the systematic fragment holding stripe i of the data,
+// and (0, false) otherwise.
+func (f *Frag) Systematic() (int, bool) {
+	i := sysrow(f.A)
+	if i < 0 {
+		return 0, false
+	}
+	return i, true
+}
+
+// sysrow returns i if a is row i of the identity matrix, and -1 otherwise.
+func sysrow(a []Field) int {
+	r := -1
+	for i, v := range a {
+		switch {
+		case v == 0:
+			// fine
+		case v == 1 && r < 0:
+			r = i
+		default:
+			return -1
+		}
+	}
+	return r
+}
+
+// sysfrags returns the indices in frags of the systematic fragments for m, in stripe order,
+// if they are all present, and nil otherwise.
+// Fragments that are malformed, or have a different M, are never chosen,
+// so that the decoder built from them (which reports such fragments) is not a systematic one.
+func sysfrags(frags []*Frag, m int) []int {
+	sys := make([]int, m)
+	have := make([]bool, m)
+	found := 0
+	for j, f := range frags {
+		if f.M != m || f.shape() != nil {
+			continue
+		}
+		if i := sysrow(f.A); i >= 0 && i < m && !have[i] {
+			sys[i] = j
+			have[i] = true
+			found++
+		}
+	}
+	if found != m {
+		return nil
+	}
+	return sys
+}
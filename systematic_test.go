@@ -0,0 +1,66 @@
+package ida
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSystematicEncode(t *testing.T) {
+	data := []byte("systematic fragments hold the data itself!")
+	const m, n = 4, 7
+	frags, err := SystematicEncode(data, m, n)
+	if err != nil {
+		t.Fatalf("SystematicEncode: %v", err)
+	}
+	for i, f := range frags {
+		s, ok := f.Systematic()
+		if ok != (i < m) || ok && s != i {
+			t.Errorf("fragment %d: Systematic() = %d, %v", i, s, ok)
+		}
+		if badfrag(f) {
+			t.Errorf("fragment %d: bad fragment", i)
+		}
+	}
+	subsets := [][]int{
+		{3, 1, 0, 2}, // systematic only
+		{4, 5, 6, 0}, // mostly parity
+		{6, 2, 5, 1},
+		{1, 0, 4, 5},
+		{3, 2, 1, 4}, // first m-1 systematic, out of order
+	}
+	for _, s := range subsets {
+		var set []*Frag
+		for _, i := range s {
+			set = append(set, frags[i])
+		}
+		zot, err := Reconstruct(set)
+		if err != nil {
+			t.Errorf("Reconstruct %v: %v", s, err)
+			continue
+		}
+		if !bytes.Equal(zot, data) {
+			t.Errorf("Reconstruct %v: want %q got %q", s, data, zot)
+		}
+	}
+}
+
+func TestSystematicCorruptLead(t *testing.T) {
+	data := []byte("one bad apple at the front of the barrel")
+	frags, err := SystematicEncode(data, 3, 5)
+	if err != nil {
+		t.Fatalf("SystematicEncode: %v", err)
+	}
+	for _, m := range []int{-1, 0, 1 << 40} {
+		lead := frags[0].Clone()
+		lead.M = m
+		set := []*Frag{lead, frags[1], frags[2]}
+		if _, err := Reconstruct(set); err == nil {
+			t.Errorf("M=%d: Reconstruct of a corrupt set: want error", m)
+		}
+		set = append(set, frags[3])
+		zot, err := Reconstruct(set)
+		if err != nil || !bytes.Equal(zot, data) {
+			t.Errorf("M=%d: Reconstruct with a spare: want %q got %q, %v", m, data, zot, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ida
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Verify checks a set of fragments against each other, using the redundancy in the set
+// to locate fragments that are corrupt even though their values are in range and
+// their CRCs (if any) check. It reconstructs the data words from several m-subsets of frags
+// in turn, and takes as the consensus the result that agrees with most fragments,
+// returning those fragments as good, and the indices in frags of those that disagree as bad.
+//
+// At least m+1 fragments are needed to detect a corrupt fragment,
+// and m+2t to locate (and so correct) t of them, although more might be needed,
+// since only n subsets are tried for n fragments.
+// Nil fragments are ignored, being neither good nor bad, and m is decided by majority vote, as for [Reconstruct].
+// Verify returns ErrTooFewFragments if there are fewer than m+1 fragments,
+// and ErrNoConsistency if no result agrees with more than m of them.
+// Frags must otherwise be consistent, as [Consistent] would return.
+func Verify(all []*Frag) (good []*Frag, bad []int, err error) {
+	b, err := vote(all, 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	var frags []*Frag
+	var index []int // index[i] is the index in all of frags[i]
+	for i, f := range all {
+		if f != nil {
+			frags = append(frags, f)
+			index = append(index, i)
+		}
+	}
+	if len(frags) < b.m+1 {
+		return nil, nil, ErrTooFewFragments
+	}
+	n := len(frags)
+	var best []bool
+	nbest := 0
+	rot := make([]*Frag, n)
+	for s := 0; s < n && nbest < n; s++ {
+		copy(rot, frags[s:])
+		copy(rot[n-s:], frags[0:s])
+		sel, err := SelectIndependent(rot)
+		if err != nil {
+			continue
+		}
+		d, err := newDecoder(sel)
+		if err != nil {
+			continue
+		}
+		words, err := d.words()
+		if err != nil {
+			continue
+		}
+		agree := make([]bool, n)
+		na := 0
+		for i, f := range frags {
+			if encodes(words, f) {
+				agree[i] = true
+				na++
+			}
+		}
+		if na > nbest {
+			best, nbest = agree, na
+		}
+	}
+	if nbest <= b.m {
+		return nil, nil, ErrNoConsistency
+	}
+	for i, f := range frags {
+		if best[i] {
+			good = append(good, f)
+		} else {
+			bad = append(bad, index[i])
+		}
+	}
+	return good, bad, nil
+}
+
+// words returns all the data words, column by column.
+func (d *decoder) words() ([]Field, error) {
+	words := make([]Field, d.fraglen*d.m)
+	for k := 0; k < d.fraglen; k++ {
+		if err := d.column(k, words[k*d.m:(k+1)*d.m]); err != nil {
+			return nil, err
+		}
+	}
+	return words, nil
+}
+
+// encodes returns true if f's Enc values are the encoding of words by its row.
+func encodes(words []Field, f *Frag) bool {
+	m := len(f.A)
+	if len(words) != m*len(f.Enc) {
+		return false
+	}
+	for k, e := range f.Enc {
+		c := zero
+		for j, a := range f.A {
+			c = c.add(words[k*m+j].mul(a))
+		}
+		if int(c) != e {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyDigest reconstructs the data encoded by frags, as [Reconstruct] would, and returns true
+// if it matches the fragments' majority Digest, without returning or keeping the data:
+// it is decoded a block at a time, as by [ReconstructStream], and each block discarded once hashed,
+// so that a store can scrub its objects in the background in a fixed amount of memory.
+// It returns false and ErrNoDigest if the fragments have no Digest,
+// false and a nil error if the data does not match, and false with the error
+// if the fragments cannot be decoded at all.
+func VerifyDigest(frags []*Frag) (bool, error) {
+	d, err := newDecoder(frags)
+	if err != nil {
+		return false, err
+	}
+	if d.digest == nil {
+		return false, ErrNoDigest
+	}
+	_, err = d.stream(context.Background(), io.Discard, nil)
+	if errors.Is(err, ErrDigestMismatch) {
+		return false, nil
+	}
+	return err == nil, err
+}
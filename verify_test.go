@@ -0,0 +1,89 @@
+package ida
+
+import (
+	"slices"
+	"testing"
+)
+
+// corrupt changes f's Enc values in range, and updates its CRC to match.
+func corrupt(f *Frag) {
+	f.Enc[len(f.Enc)/2] = (f.Enc[len(f.Enc)/2] + 1) % Prime
+	f.CRC = f.checksum()
+}
+
+func TestVerify(t *testing.T) {
+	data := []byte("a bit flipped in a fragment that still passes every local check")
+	frags, err := Encode(data, 3, 7)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	good, bad, err := Verify(frags)
+	if err != nil || len(good) != 7 || len(bad) != 0 {
+		t.Errorf("Verify of good set: %d good, bad %v, %v", len(good), bad, err)
+	}
+	corrupt(frags[0])
+	corrupt(frags[4])
+	good, bad, err = Verify(frags)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if want := []int{0, 4}; !slices.Equal(bad, want) {
+		t.Errorf("Verify: want bad %v got %v", want, bad)
+	}
+	zot, err := Reconstruct(good)
+	if err != nil || string(zot) != string(data) {
+		t.Errorf("Reconstruct of good fragments: %q, %v", zot, err)
+	}
+	if _, _, err := Verify(frags[0:3]); err != ErrTooFewFragments {
+		t.Errorf("Verify with m fragments: want %v got %v", ErrTooFewFragments, err)
+	}
+	// with m+1 fragments, corruption is detected but cannot be located
+	if _, _, err := Verify(frags[3:7]); err != ErrNoConsistency {
+		t.Errorf("Verify with m+1 fragments: want %v got %v", ErrNoConsistency, err)
+	}
+	// nil fragments are skipped, and the indices of bad ones are still in the set given
+	withNil := []*Frag{nil, frags[0], frags[1], nil, frags[2], frags[3], frags[4], frags[5], frags[6]}
+	good, bad, err = Verify(withNil)
+	if err != nil || len(good) != 5 {
+		t.Fatalf("Verify with nil: %d good, %v", len(good), err)
+	}
+	if want := []int{1, 6}; !slices.Equal(bad, want) {
+		t.Errorf("Verify with nil: want bad %v got %v", want, bad)
+	}
+	for _, fs := range [][]*Frag{nil, {nil}, {nil, nil, nil, nil}} {
+		if _, _, err := Verify(fs); err != ErrTooFewFragments {
+			t.Errorf("Verify(%v): want %v got %v", fs, ErrTooFewFragments, err)
+		}
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	data := make([]byte, 3*streamBlock+17) // several blocks
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	frags, err := Encode(data, 4, 6)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if ok, err := VerifyDigest(frags); !ok || err != nil {
+		t.Errorf("VerifyDigest: want true, nil got %v, %v", ok, err)
+	}
+	bad := slices.Clone(frags[0:4])
+	bad[2] = bad[2].Clone()
+	corrupt(bad[2])
+	if ok, err := VerifyDigest(bad); ok {
+		t.Errorf("VerifyDigest of corrupt set: want false got %v, %v", ok, err)
+	}
+	nod := make([]*Frag, 4)
+	for i, f := range frags[0:4] {
+		nod[i] = f.Clone()
+		nod[i].Digest = nil
+	}
+	if ok, err := VerifyDigest(nod); ok || err != ErrNoDigest {
+		t.Errorf("VerifyDigest without Digest: want false, %v got %v, %v", ErrNoDigest, ok, err)
+	}
+	if ok, err := VerifyDigest(frags[0:3]); ok || err != ErrTooFewFragments {
+		t.Errorf("VerifyDigest of 3: want false, %v got %v, %v", ErrTooFewFragments, ok, err)
+	}
+}
@@ -1,8 +1,11 @@
 package ida
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"strings"
 )
@@ -26,37 +29,279 @@ const MaxVal Field = Prime - 1
 const zero Field = 0
 
 // operations in GF(Prime) (ie, mod Prime)
+// Since Prime is 2¹⁶+1, 2¹⁶ ≡ -1, and a product hi·2¹⁶+lo reduces to lo-hi without a division.
+// The reductions use sign masks instead of branches, so add, sub and mul take the same time
+// whatever their operands. Built with the crypto tag, div does too, by computing inverses
+// instead of looking them up (see inv_fermat.go). That is several times slower,
+// and matters only when the field values are secret, as in secret sharing.
 
 func (a Field) div(b Field) Field {
-	return a.mul(Field(invtab[b]))
+	countDiv()
+	return a.mul(b.inv())
 }
 
 func (a Field) mul(b Field) Field {
-	return Field((uint64(a) * uint64(b)) % Prime)
+	countMul()
+	x := uint64(a) * uint64(b) // at most 2³², when a = b = MaxVal
+	r := int64(x&0xFFFF) - int64(x>>16)
+	r += Prime & (r >> 63)
+	return Field(r)
 }
 
 func (a Field) sub(b Field) Field {
-	return ((a - b) + Prime) % Prime
+	countAdd()
+	r := int32(a) - int32(b)
+	r += Prime & (r >> 31)
+	return Field(r)
 }
 
 func (a Field) add(b Field) Field {
-	return (a + b) % Prime
+	countAdd()
+	r := int32(a+b) - Prime
+	r += Prime & (r >> 31)
+	return Field(r)
 }
 
-// randomVec returns a slice of length m containing random Field values in the interval [1, MaxVal].
-func randomVec(m int) []Field {
+// The exported operations below mirror the internal ones, for use by other packages.
+// Their operands must be elements of the field, in the interval [0, MaxVal].
+
+// Add returns a+b in the field.
+func (a Field) Add(b Field) Field {
+	return a.add(b)
+}
+
+// Sub returns a-b in the field.
+func (a Field) Sub(b Field) Field {
+	return a.sub(b)
+}
+
+// Mul returns a×b in the field.
+func (a Field) Mul(b Field) Field {
+	return a.mul(b)
+}
+
+// Div returns a/b in the field. Division by zero is defined to yield zero.
+func (a Field) Div(b Field) Field {
+	return a.div(b)
+}
+
+// DivChecked returns a/b in the field, or ErrDivideByZero if b is zero.
+func (a Field) DivChecked(b Field) (Field, error) {
+	if b == 0 {
+		return zero, ErrDivideByZero
+	}
+	return a.div(b), nil
+}
+
+// Inverse returns the multiplicative inverse of a, or zero if a is zero.
+func (a Field) Inverse() Field {
+	return a.inv()
+}
+
+// VerifyTables returns an error if the arithmetic tables are wrong:
+// the table of inverses in zptab.go, generated by cmd/mkidatab.go, must give a·a⁻¹ = 1
+// for every non-zero a, and the log and antilog tables of GF65536 must agree.
+// Built with the idanotab or crypto tag, it checks the computed inverses instead.
+// It takes a millisecond or so, and is meant for tests, or a check at startup.
+func VerifyTables() error {
+	if inv := zero.inv(); inv != 0 {
+		return fmt.Errorf("%w: inverse of 0 is %d", ErrBadTable, inv)
+	}
+	for a := Field(1); a <= MaxVal; a++ {
+		if inv := a.inv(); inv.mul(a) != 1 {
+			return fmt.Errorf("%w: inverse of %d is %d", ErrBadTable, a, inv)
+		}
+	}
+	gfOnce.Do(gfTables)
+	for x := 1; x < 1<<16; x++ {
+		if int(gfExp[gfLog[x]]) != x {
+			return fmt.Errorf("%w: GF65536 log of %d is %d", ErrBadTable, x, gfLog[x])
+		}
+	}
+	return nil
+}
+
+// inverse returns the multiplicative inverse of a, computed as a^(Prime-2) by Fermat's little theorem,
+// using square-and-multiply; the inverse of 0 is 0.
+// It is used instead of the table in zptab.go when built with the idanotab or crypto tag,
+// saving the table's space (256KiB) at the cost of 16 multiplications per division.
+func (a Field) inverse() Field {
+	return a.Pow(Prime - 2)
+}
+
+// Pow returns a^e in the field, using square-and-multiply.
+// A negative exponent raises the inverse of a to -e; 0^0 is 1.
+func (a Field) Pow(e int) Field {
+	u := uint(e)
+	if e < 0 {
+		a = a.inv()
+		u = uint(-(e + 1)) + 1 // -e overflows for math.MinInt
+	}
+	r := Field(1)
+	for ; u != 0; u >>= 1 {
+		if u&1 != 0 {
+			r = r.mul(a)
+		}
+		a = a.mul(a)
+	}
+	return r
+}
+
+// Sqrt returns a square root of a and true, if a is a quadratic residue (or zero),
+// and (0, false) otherwise.
+// Since Prime ≡ 1 mod 4, there is no closed form for the root,
+// so Sqrt uses the Tonelli-Shanks algorithm.
+// The other square root is the negation of the one returned.
+func (a Field) Sqrt() (Field, bool) {
+	if a == 0 {
+		return 0, true
+	}
+	if a.Pow((Prime-1)/2) != 1 {
+		return 0, false
+	}
+	// Prime-1 = q·2^s with q odd
+	q, s := Prime-1, 0
+	for q%2 == 0 {
+		q /= 2
+		s++
+	}
+	z := Field(2)
+	for z.Pow((Prime-1)/2) != MaxVal { // find a non-residue
+		z++
+	}
+	c := z.Pow(q)
+	t := a.Pow(q)
+	r := a.Pow((q + 1) / 2)
+	for t != 1 {
+		i := 0
+		for t2 := t; t2 != 1; t2 = t2.mul(t2) {
+			i++
+		}
+		b := c
+		for j := 0; j < s-i-1; j++ {
+			b = b.mul(b)
+		}
+		s = i
+		c = b.mul(b)
+		t = t.mul(c)
+		r = r.mul(b)
+	}
+	return r, true
+}
+
+// pkgRand, if not nil, replaces the default source of math/rand for random encoding rows (see SetRandSource).
+var pkgRand *rand.Rand
+
+// SetRandSource makes the package draw the random encoding rows of Fragment, Encode, and Encoders without
+// their own source, from src instead of the default source of math/rand, so that their output is reproducible,
+// for tests and golden files. SetRandSource(nil) restores the default source.
+// It must not be called concurrently with any other function of the package, and after it,
+// with src not nil, none of those functions may be called concurrently, since a rand.Rand is not safe for that.
+// It does not affect the secure variants, which always use crypto/rand.
+func SetRandSource(src rand.Source) {
+	if src == nil {
+		pkgRand = nil
+		return
+	}
+	pkgRand = rand.New(src)
+}
+
+// randomVec returns a slice of length m containing random Field values in the interval [1, MaxVal],
+// drawn from rnd, or from the package source if rnd is nil.
+func randomVec(rnd *rand.Rand, m int) []Field {
 	a := make([]Field, m)
+	src := randSource(rnd)
 	for i := range a {
-		a[i] = Field(rand.Intn(int(MaxVal))) + 1 // ensure no zero-value elements: 1..MaxVal
+		a[i] = randField(src, uint32(MaxVal)) // ensure no zero-value elements: 1..MaxVal
 	}
 	return a
 }
 
+// randSource returns a source of random 32-bit values from rnd, or from the package source if rnd is nil.
+func randSource(rnd *rand.Rand) func() uint32 {
+	if rnd == nil {
+		rnd = pkgRand
+	}
+	if rnd == nil {
+		return rand.Uint32
+	}
+	return rnd.Uint32
+}
+
+// randField returns a value uniformly distributed over the interval [1, k], for k ≥ 1,
+// taking uniformly distributed 32-bit samples from src.
+// Samples in the final partial multiple of k are rejected, and others reduced mod k,
+// so there is no modulo bias, whatever k is. At most one sample in 2¹⁶ is rejected for k ≤ 2¹⁶+1.
+func randField(src func() uint32, k uint32) Field {
+	limit := (1 << 32) / uint64(k) * uint64(k)
+	for {
+		v := uint64(src())
+		if v < limit {
+			return Field(v%uint64(k)) + 1
+		}
+	}
+}
+
+// secureVec returns a slice of length m containing Field values in the interval [1, MaxVal]
+// drawn uniformly from crypto/rand by randField, or an error if the entropy source fails.
+func secureVec(m int) ([]Field, error) {
+	var err error
+	var buf [4]byte
+	src := func() uint32 {
+		if err == nil {
+			_, err = io.ReadFull(crand.Reader, buf[:])
+		}
+		if err != nil {
+			return 0 // accepted, but discarded below
+		}
+		return binary.LittleEndian.Uint32(buf[:])
+	}
+	a := make([]Field, m)
+	for i := range a {
+		a[i] = randField(src, uint32(MaxVal))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
 var (
-	ErrNonSquare = errors.New("decoding matrix must be square")
-	ErrZeroPivot = errors.New("zero pivot value in decoding matrix")
+	ErrNonSquare      = errors.New("decoding matrix must be square")
+	ErrZeroPivot      = errors.New("zero pivot value in decoding matrix")
+	ErrSingularMatrix = errors.New("singular decoding matrix")
+	ErrDivideByZero   = errors.New("division by zero")
+	ErrBadTable       = errors.New("arithmetic table is wrong")
 )
 
+// SingularMatrixError is returned when a matrix has no inverse, and lists the rows that depend on earlier rows.
+// The fragments with those rows add nothing, and [SelectIndependent] can choose others in their place.
+// It matches ErrSingularMatrix, and ErrZeroPivot as it did before, with errors.Is.
+type SingularMatrixError struct {
+	Rows []int // the dependent rows, in order
+}
+
+func (e *SingularMatrixError) Error() string {
+	return fmt.Sprintf("singular decoding matrix: rows %v depend on earlier rows", e.Rows)
+}
+
+func (e *SingularMatrixError) Is(target error) bool {
+	return target == ErrSingularMatrix || target == ErrZeroPivot
+}
+
+// singular returns a SingularMatrixError for a square matrix a found to be singular,
+// listing the rows that a basis of the preceding rows already spans.
+func (a Matrix) singular() error {
+	var b basis
+	e := &SingularMatrixError{}
+	for r, row := range a {
+		if !b.add(row) {
+			e.Rows = append(e.Rows, r)
+		}
+	}
+	return e
+}
+
 // NewMatrix returns a new decoding matrix of rank m.
 func NewMatrix(m int) Matrix {
 	return make(Matrix, m)
@@ -67,33 +312,67 @@ func NewMatrix(m int) Matrix {
 // be inverted in O(m^2) operations, compared to O(m^3) for the following,
 // but m is small enough it doesn't seem worth the added complication,
 // and it's only done once per fragment set.
-// Invert returns an error if there's a zero pivot value or non-square matrix.
+// (See [Matrix.InvertCauchy] for that, when the matrix is known to be in Cauchy form.)
+// Invert returns an error if the matrix is non-square, or singular (no non-zero pivot can be found),
+// in which case the error is a [*SingularMatrixError].
+// A pivot that becomes zero after it was found, which only a corrupted matrix can cause,
+// gives an error matching ErrDivideByZero instead.
 func (a Matrix) Invert() (Matrix, error) {
+	m := len(a)
+	scratch := make(Matrix, m)
+	for r := range scratch {
+		scratch[r] = make([]Field, 2*m)
+	}
+	return a.InvertInto(scratch)
+}
+
+// InvertInto is like Invert but does the work in scratch, which must have m rows with capacity for at least 2m elements
+// for an m×m matrix a, so that repeated inversions can reuse the same storage.
+// The inverse returned is held in scratch (only its m row headers are allocated),
+// so it is valid only until scratch is next used.
+// InvertInto returns ErrDimension if scratch is too small.
+func (a Matrix) InvertInto(scratch Matrix) (Matrix, error) {
 	m := len(a) // it's square
-	out := make(Matrix, m)
+	if len(scratch) < m {
+		return nil, ErrDimension
+	}
+	out := scratch[0:m]
 	// copy each row and add the adjacent identity matrix
 	for r := 0; r < m; r++ {
 		if len(a[r]) != m {
 			return nil, ErrNonSquare
 		}
-		out[r] = make([]Field, m*2)
+		if cap(out[r]) < 2*m {
+			return nil, ErrDimension
+		}
+		out[r] = out[r][0 : 2*m]
 		copy(out[r], a[r])
+		clear(out[r][m:])
 		out[r][m+r] = 1 // identity matrix
 	}
 	for r := 0; r < m; r++ {
-		x := out[r][r] // by construction, cannot be zero, unless later corrupted
+		// rows of the identity matrix (from systematic fragments) can leave a zero on the diagonal,
+		// so swap in a later row with a non-zero pivot; if there is none, the matrix is singular
+		for p := r + 1; out[r][r] == 0 && p < m; p++ {
+			if out[p][r] != 0 {
+				out[r], out[p] = out[p], out[r]
+			}
+		}
+		x := out[r][r]
 		if x == 0 {
-			return nil, ErrZeroPivot
+			return nil, a.singular()
 		}
 		for c := 0; c < 2*m; c++ {
 			out[r][c] = out[r][c].div(x)
 		}
 		for r1 := 0; r1 < m; r1++ {
 			if r1 != r {
-				if out[r][r] == 0 {
-					return nil, ErrZeroPivot
+				// out[r][r] is 1 by now, unless the arithmetic has gone wrong,
+				// as it can with elements outside the field, and that is not a singular matrix
+				y, err := out[r1][r].DivChecked(out[r][r])
+				if err != nil {
+					return nil, fmt.Errorf("%w: pivot of row %d", err, r)
 				}
-				y := out[r1][r].div(out[r][r])
 				for c := 0; c < 2*m; c++ {
 					out[r1][c] = out[r1][c].sub(y.mul(out[r][c]))
 				}
@@ -101,10 +380,11 @@ func (a Matrix) Invert() (Matrix, error) {
 		}
 	}
 	// remove the adjacent temporary matrix (now in front)
+	inv := make(Matrix, m)
 	for r := 0; r < m; r++ {
-		out[r] = out[r][m:]
+		inv[r] = out[r][m:]
 	}
-	return out, nil
+	return inv, nil
 }
 
 func (m Matrix) String() string {
@@ -1,6 +1,11 @@
 package ida
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
 	"testing"
 )
 
@@ -63,9 +68,12 @@ func TestZp(t *testing.T) {
 			if a == 0 {
 				return true
 			}
-			b := invtab[a]
+			b := a.inv()
 			return a.mul(b) == 1 && b.mul(a) == 1
 		})
+		all1(t, "* Fermat inverse", func(a Field) bool {
+			return a.inverse() == a.inv()
+		})
 		all3(t, "* distributes", func(a, b, c Field) bool {
 			return a.mul(b.add(c)) == a.mul(b).add(a.mul(c))
 		})
@@ -75,5 +83,263 @@ func TestZp(t *testing.T) {
 	})
 }
 
-//func BenchmarkTestZp(b *testing.B) {
-//}
+func TestExported(t *testing.T) {
+	all2(t, "exported", func(a, b Field) bool {
+		return a.Add(b) == a.add(b) && a.Sub(b) == a.sub(b) && a.Mul(b) == a.mul(b) && a.Div(b) == a.div(b)
+	})
+	all1(t, "Inverse", func(a Field) bool {
+		return a == 0 && a.Inverse() == 0 || a.Mul(a.Inverse()) == 1
+	})
+	if r := Field(5).Div(0); r != 0 {
+		t.Errorf("5/0: want 0; got %d", r)
+	}
+}
+
+func TestVerifyTables(t *testing.T) {
+	if err := VerifyTables(); err != nil {
+		t.Fatalf("VerifyTables: %v", err)
+	}
+}
+
+func TestDivChecked(t *testing.T) {
+	all2(t, "DivChecked", func(a, b Field) bool {
+		q, err := a.DivChecked(b)
+		if b == 0 {
+			return q == 0 && err == ErrDivideByZero
+		}
+		return q == a.div(b) && err == nil
+	})
+}
+
+func TestPow(t *testing.T) {
+	for _, a := range []Field{0, 1, 2, 3, 12345, MaxVal - 1, MaxVal} {
+		p := Field(1)
+		for e := 0; e < 100; e++ {
+			if r := a.Pow(e); r != p {
+				t.Errorf("%d^%d: want %d got %d", a, e, p, r)
+			}
+			if a != 0 {
+				if r := a.Pow(-e).mul(p); r != 1 {
+					t.Errorf("%d^-%d × %d^%d: want 1 got %d", a, e, a, e, r)
+				}
+			}
+			p = p.mul(a)
+		}
+		// the extremes, where -e overflows: a^MinInt × a^MaxInt × a = 1
+		if r := a.Pow(math.MinInt).mul(a.Pow(math.MaxInt)).mul(a); a != 0 && r != 1 {
+			t.Errorf("%d^MinInt × %d^MaxInt × %d: want 1 got %d", a, a, a, r)
+		}
+		if a == 0 && a.Pow(math.MinInt) != 0 {
+			t.Errorf("0^MinInt: want 0 got %d", a.Pow(math.MinInt))
+		}
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	n := 0
+	all1(t, "Sqrt", func(a Field) bool {
+		r, ok := a.Sqrt()
+		if !ok {
+			return r == 0
+		}
+		n++
+		return r.mul(r) == a
+	})
+	if want := (Prime-1)/2 + 1; n != want {
+		t.Errorf("Sqrt: want %d residues got %d", want, n)
+	}
+}
+
+func BenchmarkInvert(b *testing.B) {
+	rnd := rand.New(rand.NewSource(79))
+	for _, m := range []int{2, 4, 8, 16, 32, 64, 128} {
+		a := NewMatrix(m)
+		for i := range a {
+			a[i] = randomVec(rnd, m)
+		}
+		b.Run(fmt.Sprintf("m=%d", m), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := a.Invert(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("into/m=%d", m), func(b *testing.B) {
+			scratch := NewMatrix(m)
+			for i := range scratch {
+				scratch[i] = make([]Field, 2*m)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := a.InvertInto(scratch); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInverse(b *testing.B) {
+	b.Run("inv", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Field(i%int(MaxVal) + 1).inv()
+		}
+	})
+	b.Run("inverse", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Field(i%int(MaxVal) + 1).inverse()
+		}
+	})
+}
+
+// mulMod is the straightforward version of mul, for comparison.
+func mulMod(a, b Field) Field {
+	return Field((uint64(a) * uint64(b)) % Prime)
+}
+
+func TestReduce(t *testing.T) {
+	all2(t, "mul", func(a, b Field) bool {
+		return a.mul(b) == mulMod(a, b) && b.mul(a) == mulMod(a, b)
+	})
+	all2(t, "add", func(a, b Field) bool {
+		return a.add(b) == (a+b)%Prime && b.add(a) == (a+b)%Prime
+	})
+	all2(t, "sub", func(a, b Field) bool {
+		return a.sub(b) == (a+Prime-b)%Prime && b.sub(a) == (b+Prime-a)%Prime
+	})
+}
+
+var sink Field
+
+func BenchmarkMul(b *testing.B) {
+	x := Field(1)
+	for i := 0; i < b.N; i++ {
+		x = x.mul(MaxVal - Field(i&0xFF))
+	}
+	sink = x
+}
+
+func BenchmarkMulMod(b *testing.B) {
+	x := Field(1)
+	for i := 0; i < b.N; i++ {
+		x = mulMod(x, MaxVal-Field(i&0xFF))
+	}
+	sink = x
+}
+
+func TestSingular(t *testing.T) {
+	a := Matrix{{1, 2, 3}, {4, 5, 6}, {2, 4, 6}, {5, 7, 10}}
+	_, err := Matrix{a[0], a[1], a[2]}.Invert()
+	var se *SingularMatrixError
+	if !errors.As(err, &se) || !slices.Equal(se.Rows, []int{2}) {
+		t.Fatalf("Invert: want rows [2] got %v", err)
+	}
+	if !errors.Is(err, ErrSingularMatrix) || !errors.Is(err, ErrZeroPivot) {
+		t.Errorf("Invert: %v does not match ErrSingularMatrix and ErrZeroPivot", err)
+	}
+	frags := make([]*Frag, len(a))
+	for i, row := range a {
+		frags[i] = &Frag{Len: 6, M: 3, A: row, Enc: []int{0}}
+	}
+	if _, err := Reconstruct(frags[0:3]); !errors.As(err, &se) || !slices.Equal(se.Rows, []int{2}) {
+		t.Errorf("Reconstruct: want rows [2] got %v", err)
+	}
+	if _, err := Reconstruct(frags); err != nil {
+		t.Errorf("Reconstruct with a spare fragment: %v", err)
+	}
+}
+
+func TestInvertInto(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	scratch := NewMatrix(8)
+	for i := range scratch {
+		scratch[i] = make([]Field, 16)
+	}
+	for _, m := range []int{8, 3, 1, 8} {
+		a := NewMatrix(m)
+		for i := range a {
+			a[i] = randomVec(rnd, m)
+		}
+		a[0] = make([]Field, m)
+		a[0][m-1] = 1 // needs a row swap
+		want, err1 := a.Invert()
+		got, err2 := a.InvertInto(scratch)
+		if err1 != nil || err2 != nil || got.String() != want.String() {
+			t.Errorf("m=%d: InvertInto differs from Invert: %v %v", m, err1, err2)
+		}
+	}
+	a := Matrix{{1, 2}, {3, 4}}
+	if n := testing.AllocsPerRun(10, func() { a.InvertInto(scratch) }); n != 1 {
+		t.Errorf("InvertInto: %v allocations", n)
+	}
+	if _, err := a.InvertInto(scratch[0:1]); err != ErrDimension {
+		t.Errorf("InvertInto short scratch: want %v got %v", ErrDimension, err)
+	}
+	if _, err := a.InvertInto(Matrix{make([]Field, 3), make([]Field, 3)}); err != ErrDimension {
+		t.Errorf("InvertInto narrow scratch: want %v got %v", ErrDimension, err)
+	}
+}
+
+func TestSetRandSource(t *testing.T) {
+	defer SetRandSource(nil)
+	data := []byte("the same every time")
+	run := func() []*Frag {
+		SetRandSource(rand.NewSource(73))
+		frags, err := Encode(data, 3, 5)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		return append(frags, Fragment(data, 2))
+	}
+	a, b := run(), run()
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			t.Errorf("fragment %d differs with the same source", i)
+		}
+	}
+	SetRandSource(nil)
+	if c := Fragment(data, 2); c.Equal(a[len(a)-1]) {
+		t.Errorf("default source repeated the seeded fragment")
+	}
+}
+
+func TestRandFieldUniform(t *testing.T) {
+	const k = uint32(MaxVal)
+	const per = 30 // expected samples per value
+	counts := make([]int, k+1)
+	src := rand.New(rand.NewSource(74)).Uint32
+	for i := 0; i < per*int(k); i++ {
+		v := randField(src, k)
+		if v < 1 || v > MaxVal {
+			t.Fatalf("randField: %d out of range", v)
+		}
+		counts[v]++
+	}
+	// χ² with k-1 degrees of freedom has mean k-1 and variance 2(k-1); allow 5 standard deviations
+	chi := 0.0
+	for _, c := range counts[1:] {
+		d := float64(c - per)
+		chi += d * d / per
+	}
+	df := float64(k - 1)
+	if sd := math.Sqrt(2 * df); math.Abs(chi-df) > 5*sd {
+		t.Errorf("χ² = %.0f, want %.0f ± %.0f", chi, df, 5*sd)
+	}
+}
+
+func TestRandFieldRejects(t *testing.T) {
+	// with k = 3, samples from 2³²-1 (the final partial multiple) must be rejected
+	samples := []uint32{math.MaxUint32, 4, 5}
+	src := func() uint32 {
+		v := samples[0]
+		samples = samples[1:]
+		return v
+	}
+	if v := randField(src, 3); v != 2 {
+		t.Errorf("randField: want 2 (from 4) got %d", v)
+	}
+	if len(samples) != 1 {
+		t.Errorf("randField consumed %d samples, want 2", 2-len(samples))
+	}
+}
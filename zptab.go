@@ -1,5 +1,7 @@
 // Coded generated by mkidatab; DO NOT EDIT
 
+//go:build !idanotab && !crypto
+
 package ida
 
 var invtab = []Field{